@@ -0,0 +1,328 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const cliUsage = `Usage: lazyrmss [command] [args...]
+
+Without a command, lazyrmss starts the interactive TUI.
+
+Commands:
+  list                              List services, their state and addons
+  enable <category>/<service>       Include a service in the composition
+  disable <category>/<service>      Exclude a service from the composition
+  addon <service> [+name] [-name]   Activate (+) or deactivate (-) addons
+  render [<category>/<service>]     Print the resolved compose YAML
+  up [--force] [args...]            docker compose up -d for enabled services;
+                                    refuses schema-invalid configs unless forced
+  down [args...]                    docker compose down
+  stop | start | restart [args...]  docker compose stop / start / restart
+  pull | build [args...]            docker compose pull / build
+  help                              Show this help
+
+Services may be referenced as <category>/<service> or, when the name is
+unique across categories, as <service>.
+`
+
+// composeCommands maps CLI subcommands to the docker compose arguments they
+// run, mirroring the global actions of the TUI.
+var composeCommands = map[string][]string{
+	"up":      {"up", "-d"},
+	"down":    {"down"},
+	"stop":    {"stop"},
+	"start":   {"start"},
+	"restart": {"restart"},
+	"pull":    {"pull"},
+	"build":   {"build"},
+}
+
+// runCLI executes a headless subcommand and returns the process exit code.
+func (a *App) runCLI(args []string) int {
+	if err := a.execCLI(os.Stdout, args); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func (a *App) execCLI(out io.Writer, args []string) error {
+	cmd, rest := args[0], args[1:]
+
+	if composeArgs, ok := composeCommands[cmd]; ok {
+		if cmd == "up" {
+			var force bool
+			rest, force = removeArg(rest, "--force")
+			if problems := a.schemaProblems(); len(problems) > 0 && !force {
+				for _, p := range problems {
+					fmt.Fprintln(os.Stderr, p)
+				}
+				return fmt.Errorf("compose configuration is invalid; use 'up --force' to run anyway")
+			}
+		}
+		return a.runComposeHeadless(append(append([]string{}, composeArgs...), rest...)...)
+	}
+
+	switch cmd {
+	case "list", "ls":
+		a.cliList(out)
+		return nil
+	case "enable", "disable":
+		if len(rest) == 0 {
+			return fmt.Errorf("%s: missing service", cmd)
+		}
+		for _, ref := range rest {
+			opt, err := a.findOption(ref)
+			if err != nil {
+				return err
+			}
+			a.cliSetEnabled(opt, cmd == "enable")
+		}
+		return a.saveState()
+	case "addon", "addons":
+		if len(rest) == 0 {
+			return fmt.Errorf("addon: missing service")
+		}
+		opt, err := a.findOption(rest[0])
+		if err != nil {
+			return err
+		}
+		notes, err := applyAddonArgs(opt, rest[1:])
+		if err != nil {
+			return err
+		}
+		for _, note := range notes {
+			fmt.Fprintln(os.Stderr, note)
+		}
+		return a.saveState()
+	case "render":
+		return a.cliRender(out, rest)
+	}
+
+	return fmt.Errorf("unknown command %q (see 'lazyrmss help')", cmd)
+}
+
+// cliSetEnabled enables opt along with its dependencies, or disables it
+// with a warning when enabled services still depend on it.
+func (a *App) cliSetEnabled(opt *Option, enabled bool) {
+	if enabled {
+		if deps := a.disabledDependencies(opt); len(deps) > 0 {
+			for _, dep := range deps {
+				dep.Enabled = true
+			}
+			fmt.Fprintf(os.Stderr, "also enabled %s (needed by %s)\n", formatOptionRefs(deps), optionRef(opt))
+		}
+	} else if dependents := a.enabledDependents(opt); len(dependents) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: %s is required by %s\n", optionRef(opt), formatOptionRefs(dependents))
+	}
+	opt.Enabled = enabled
+}
+
+// removeArg drops every occurrence of flag from args and reports whether
+// it was present.
+func removeArg(args []string, flag string) ([]string, bool) {
+	var kept []string
+	found := false
+	for _, arg := range args {
+		if arg == flag {
+			found = true
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	return kept, found
+}
+
+func isHelpArg(arg string) bool {
+	return arg == "help" || arg == "-h" || arg == "--help"
+}
+
+// findOption resolves a "<category>/<service>" or bare "<service>" reference.
+func (a *App) findOption(ref string) (*Option, error) {
+	if catName, name, ok := strings.Cut(ref, "/"); ok {
+		for _, opt := range a.options[catName] {
+			if opt.Name == name {
+				return opt, nil
+			}
+		}
+		return nil, fmt.Errorf("service %q not found", ref)
+	}
+
+	var matches []*Option
+	for _, cat := range a.categories {
+		for _, opt := range a.options[cat.Name] {
+			if opt.Name == ref {
+				matches = append(matches, opt)
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("service %q not found", ref)
+	case 1:
+		return matches[0], nil
+	}
+	var refs []string
+	for _, opt := range matches {
+		refs = append(refs, opt.Category+"/"+opt.Name)
+	}
+	return nil, fmt.Errorf("service %q is ambiguous: %s", ref, strings.Join(refs, ", "))
+}
+
+// applyAddonArgs activates "+name" (or bare "name") and deactivates "-name"
+// addons on opt, honouring the service manifest. Names are validated before
+// any change is made, and a change the manifest refuses leaves opt as it
+// was. Dependencies toggled along the way are returned as notes.
+func applyAddonArgs(opt *Option, args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("addon: no addons given for %s", opt.Name)
+	}
+
+	type change struct {
+		name   string
+		enable bool
+	}
+	var changes []change
+	for _, arg := range args {
+		c := change{name: arg, enable: true}
+		switch {
+		case strings.HasPrefix(arg, "+"):
+			c.name = arg[1:]
+		case strings.HasPrefix(arg, "-"):
+			c.name, c.enable = arg[1:], false
+		}
+		if !opt.hasAddon(c.name) {
+			return nil, fmt.Errorf("addon %q not found for %s", c.name, opt.Name)
+		}
+		changes = append(changes, c)
+	}
+
+	before := make(map[string]bool)
+	for name := range opt.ActiveAddons {
+		before[name] = true
+	}
+
+	var notes []string
+	for _, c := range changes {
+		if !c.enable {
+			removed, err := opt.disableAddon(c.name)
+			if err != nil {
+				opt.ActiveAddons = before
+				return nil, err
+			}
+			if len(removed) > 0 {
+				notes = append(notes, fmt.Sprintf("also disabled %s (requires %s)", strings.Join(removed, ", "), c.name))
+			}
+			continue
+		}
+		added, err := opt.enableAddon(c.name)
+		if err != nil {
+			opt.ActiveAddons = before
+			return nil, err
+		}
+		if len(added) > 0 {
+			notes = append(notes, fmt.Sprintf("also enabled %s (needed by %s)", strings.Join(added, ", "), c.name))
+		}
+	}
+	return notes, nil
+}
+
+func (a *App) cliList(out io.Writer) {
+	for _, cat := range a.categories {
+		for _, opt := range a.options[cat.Name] {
+			state := "disabled"
+			if opt.Enabled {
+				state = "enabled"
+			}
+			var addons []string
+			for _, addon := range opt.Addons {
+				if opt.ActiveAddons[addon.Name] {
+					addons = append(addons, "+"+addon.Name)
+				} else {
+					addons = append(addons, "-"+addon.Name)
+				}
+			}
+			fmt.Fprintf(out, "%-32s %-9s %s\n", cat.Name+"/"+opt.Name, state, strings.Join(addons, " "))
+		}
+	}
+}
+
+func (a *App) cliRender(out io.Writer, args []string) error {
+	var data *yaml.Node
+	var err error
+	if len(args) > 0 {
+		opt, ferr := a.findOption(args[0])
+		if ferr != nil {
+			return ferr
+		}
+		data, err = resolveOption(opt, a.diagnostics)
+	} else {
+		data, err = a.buildGlobalCompose()
+	}
+	if err != nil {
+		return err
+	}
+
+	yamlStr, err := renderYAML(data)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(out, yamlStr)
+	return nil
+}
+
+// runComposeHeadless runs docker compose for every project with enabled
+// services, one after the other, with output attached to the terminal.
+func (a *App) runComposeHeadless(args ...string) error {
+	ran := false
+	for _, p := range a.projects() {
+		compose, err := a.buildCompose(p.Categories)
+		if err != nil {
+			return err
+		}
+		if len(compose.Content) == 0 {
+			continue
+		}
+		if err := a.runProjectHeadless(p, compose, args...); err != nil {
+			return err
+		}
+		ran = true
+	}
+	if !ran {
+		return fmt.Errorf("no services enabled")
+	}
+	return nil
+}
+
+func (a *App) runProjectHeadless(p project, compose *yaml.Node, args ...string) error {
+	tmpPath, err := writeComposeFile(compose)
+	if err != nil {
+		return fmt.Errorf("writing compose file: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	fmt.Fprintf(os.Stderr, "$ docker compose -p %s %s\n", p.Name, strings.Join(args, " "))
+	cmdArgs := composeArgs(p, tmpPath, args...)
+	cmd := exec.Command("docker", cmdArgs...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	if services, ok := upServices(args); ok {
+		a.diagnostics.check("state", appliedComposePath(p.Name), recordApplied(p.Name, compose, services))
+	}
+	return nil
+}
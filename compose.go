@@ -3,14 +3,11 @@ package main
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
 
-	"github.com/rivo/tview"
 	"gopkg.in/yaml.v3"
 )
 
@@ -33,7 +30,8 @@ func (a *App) discoverAll() error {
 		}
 		a.categories = append(a.categories, cat)
 
-		opts, err := discoverOptions(cat)
+		opts, err := discoverOptions(cat, a.diagnostics)
+		a.diagnostics.check("category", cat.Dir, err)
 		if err != nil {
 			continue
 		}
@@ -47,7 +45,7 @@ func (a *App) discoverAll() error {
 	return nil
 }
 
-func discoverOptions(cat Category) ([]*Option, error) {
+func discoverOptions(cat Category, diags *diagnosticLog) ([]*Option, error) {
 	entries, err := os.ReadDir(cat.Dir)
 	if err != nil {
 		return nil, err
@@ -65,8 +63,11 @@ func discoverOptions(cat Category) ([]*Option, error) {
 			Category:     cat.Name,
 			ActiveAddons: make(map[string]bool),
 		}
+		opt.Manifest, err = loadManifest(opt.Dir)
+		diags.check("manifest", filepath.Join(opt.Dir, manifestName+".yaml"), err)
 
 		files, err := os.ReadDir(opt.Dir)
+		diags.check("service", opt.Dir, err)
 		if err != nil {
 			continue
 		}
@@ -79,6 +80,8 @@ func discoverOptions(cat Category) ([]*Option, error) {
 			name := strings.TrimSuffix(f.Name(), ".yaml")
 			if name == "base" {
 				opt.BaseFile = fullPath
+			} else if name == manifestName {
+				continue
 			} else {
 				label, color := getAddonDisplay(name)
 				opt.Addons = append(opt.Addons, Addon{
@@ -90,9 +93,11 @@ func discoverOptions(cat Category) ([]*Option, error) {
 			}
 		}
 
-		sort.Slice(opt.Addons, func(i, j int) bool {
+		opt.assignGroups()
+		sort.SliceStable(opt.Addons, func(i, j int) bool {
 			return opt.Addons[i].Name < opt.Addons[j].Name
 		})
+		opt.normalizeAddons()
 
 		if opt.BaseFile != "" {
 			options = append(options, opt)
@@ -108,344 +113,307 @@ func discoverOptions(cat Category) ([]*Option, error) {
 
 // --- YAML loading and merging ---
 
-func loadYAMLFile(path string) (map[string]interface{}, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	var result map[string]interface{}
-	if err := yaml.Unmarshal(data, &result); err != nil {
-		return nil, err
-	}
-	return result, nil
+// resolveOption merges the active addons of opt into its base file. The
+// merge runs on YAML nodes, so key order and comments of the source files
+// survive and !reset and !override directives in addon files are honoured.
+func resolveOption(opt *Option, diags *diagnosticLog) (*yaml.Node, error) {
+	return traceOption(opt, nil, diags)
 }
 
-// deepMerge merges src into dst recursively.
-// Maps merge recursively, lists append, scalars from src override dst.
-func deepMerge(dst, src map[string]interface{}) map[string]interface{} {
-	if dst == nil {
-		dst = make(map[string]interface{})
-	}
-	for key, srcVal := range src {
-		dstVal, exists := dst[key]
-		if !exists {
-			dst[key] = srcVal
-			continue
-		}
-
-		srcMap, srcIsMap := srcVal.(map[string]interface{})
-		dstMap, dstIsMap := dstVal.(map[string]interface{})
-		if srcIsMap && dstIsMap {
-			dst[key] = deepMerge(dstMap, srcMap)
-			continue
-		}
-
-		srcList, srcIsList := srcVal.([]interface{})
-		dstList, dstIsList := dstVal.([]interface{})
-		if srcIsList && dstIsList {
-			dst[key] = append(dstList, srcList...)
-			continue
-		}
-
-		dst[key] = srcVal
+// traceOption resolves opt like resolveOption and, if origins is not nil,
+// records the file every node of the result was loaded from.
+func traceOption(opt *Option, origins provenance, diags *diagnosticLog) (*yaml.Node, error) {
+	base, err := loadYAMLNode(opt.BaseFile)
+	if err == nil && base.Kind != yaml.MappingNode {
+		err = fmt.Errorf("top level is not a mapping")
 	}
-	return dst
-}
-
-func resolveOption(opt *Option) (map[string]interface{}, error) {
-	result, err := loadYAMLFile(opt.BaseFile)
+	diags.check("base", opt.BaseFile, err)
 	if err != nil {
 		return nil, fmt.Errorf("loading base for %s: %w", opt.Name, err)
 	}
+	resolvePaths(base, filepath.Dir(opt.BaseFile))
+	origins.record(base, opt.BaseFile)
+	merged := applyDirectives(base)
 
 	for _, addon := range opt.Addons {
 		if !opt.ActiveAddons[addon.Name] {
 			continue
 		}
-		addonData, err := loadYAMLFile(addon.File)
+		// A broken addon is left out rather than failing the whole
+		// option; the problems list says so.
+		addonNode, err := loadYAMLNode(addon.File)
+		diags.check("addon", addon.File, err)
 		if err != nil {
 			continue
 		}
-		result = deepMerge(result, addonData)
+		resolvePaths(addonNode, filepath.Dir(addon.File))
+		origins.record(addonNode, addon.File)
+		merged = mergeNodes(merged, addonNode)
 	}
+	defaultBuildContexts(merged, filepath.Dir(opt.BaseFile))
+	return merged, nil
+}
+
+// resolutions caches resolved options, so that a refresh that needs an
+// option several times reads and merges its files only once. The cached
+// nodes are shared: callers that modify them must clone them first.
+type resolutions struct {
+	diagnostics *diagnosticLog
+	cache       map[*Option]resolution
+}
+
+type resolution struct {
+	node *yaml.Node
+	err  error
+}
+
+func (a *App) newResolutions() *resolutions {
+	return &resolutions{diagnostics: a.diagnostics, cache: make(map[*Option]resolution)}
+}
+
+// resolve returns the resolved YAML of opt, see resolveOption.
+func (r *resolutions) resolve(opt *Option) (*yaml.Node, error) {
+	res, ok := r.cache[opt]
+	if !ok {
+		res.node, res.err = resolveOption(opt, r.diagnostics)
+		r.cache[opt] = res
+	}
+	return res.node, res.err
+}
+
+// buildGlobalCompose merges the enabled services of all categories.
+func (a *App) buildGlobalCompose() (*yaml.Node, error) {
+	return a.buildCompose(a.categories)
+}
 
-	return result, nil
+// buildCompose merges the enabled services of categories into one
+// composition, as passed to docker compose: the variables of each service
+// are expanded with its own .env files.
+func (a *App) buildCompose(categories []Category) (*yaml.Node, error) {
+	return a.buildComposeFrom(categories, a.newResolutions())
 }
 
-func (a *App) buildGlobalCompose() (map[string]interface{}, error) {
-	global := make(map[string]interface{})
+// buildComposeFrom is buildCompose with the options resolved through r.
+func (a *App) buildComposeFrom(categories []Category, r *resolutions) (*yaml.Node, error) {
+	global := newMappingNode()
 
-	for _, cat := range a.categories {
+	for _, cat := range categories {
 		opts := a.options[cat.Name]
 		for _, opt := range opts {
 			if !opt.Enabled {
 				continue
 			}
-			resolved, err := resolveOption(opt)
+			resolved, err := r.resolve(opt)
 			if err != nil {
 				continue
 			}
-			global = deepMerge(global, resolved)
+			// Interpolation and merging modify nodes in place.
+			resolved = cloneNode(resolved)
+			a.interpolateForCompose(opt, resolved)
+			global = mergeNodes(global, resolved)
 		}
 	}
 
+	if a.config.EmitDependsOn {
+		a.applyComposeDependsOn(global, categories, r)
+	}
+
 	return global, nil
 }
 
-func renderYAML(data map[string]interface{}) (string, error) {
-	out, err := yaml.Marshal(data)
-	if err != nil {
+func renderYAML(data *yaml.Node) (string, error) {
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(data); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
 		return "", err
 	}
-	return string(out), nil
+	return b.String(), nil
 }
 
 // --- Resource name extraction ---
 
-func extractContainerNames(resolved map[string]interface{}) []string {
-	services, ok := resolved["services"].(map[string]interface{})
-	if !ok {
-		return nil
-	}
-	var names []string
-	for svcKey, svcVal := range services {
-		svcMap, ok := svcVal.(map[string]interface{})
-		if !ok {
-			names = append(names, svcKey)
-			continue
-		}
-		if cn, ok := svcMap["container_name"].(string); ok && cn != "" {
-			names = append(names, cn)
-		} else {
-			names = append(names, svcKey)
-		}
-	}
-	return names
+// serviceRef identifies a compose service and its explicit container name.
+type serviceRef struct {
+	Key           string
+	ContainerName string
 }
 
-func extractNetworkNames(resolved map[string]interface{}) []string {
-	networks, ok := resolved["networks"].(map[string]interface{})
-	if !ok {
+// extractServiceRefs lists the services of a resolved option in source
+// order.
+func extractServiceRefs(resolved *yaml.Node) []serviceRef {
+	services := mappingNode(resolved, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
 		return nil
 	}
-	var names []string
-	for netKey, netVal := range networks {
-		netMap, ok := netVal.(map[string]interface{})
-		if !ok {
-			names = append(names, netKey)
-			continue
-		}
-		if n, ok := netMap["name"].(string); ok && n != "" {
-			names = append(names, n)
-		} else {
-			names = append(names, netKey)
+	var refs []serviceRef
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		ref := serviceRef{Key: services.Content[i].Value}
+		if svc := services.Content[i+1]; svc.Kind == yaml.MappingNode {
+			ref.ContainerName = mappingValue(svc, "container_name")
 		}
+		refs = append(refs, ref)
 	}
-	return names
+	return refs
 }
 
-func extractVolumeNames(resolved map[string]interface{}) []string {
-	volumes, ok := resolved["volumes"].(map[string]interface{})
-	if !ok {
+// extractResourceNames returns the names Docker knows the top-level
+// networks or volumes by when created by the compose project projectName:
+// the explicit "name" if set, the key for an external resource, and
+// "<project>_<key>" otherwise.
+func extractResourceNames(resolved *yaml.Node, section, projectName string) []string {
+	resources := mappingNode(resolved, section)
+	if resources == nil || resources.Kind != yaml.MappingNode {
 		return nil
 	}
 	var names []string
-	for volKey, volVal := range volumes {
-		volMap, ok := volVal.(map[string]interface{})
-		if !ok {
-			names = append(names, volKey)
-			continue
+	for i := 0; i+1 < len(resources.Content); i += 2 {
+		key, res := resources.Content[i].Value, resources.Content[i+1]
+		name := mappingValue(res, "name")
+		if name == "" {
+			// The legacy "external: {name: ...}" form.
+			name = mappingValue(mappingNode(res, "external"), "name")
 		}
-		if n, ok := volMap["name"].(string); ok && n != "" {
-			names = append(names, n)
-		} else {
-			names = append(names, volKey)
+		switch {
+		case name != "":
+		case isExternal(res):
+			name = key
+		default:
+			name = projectName + "_" + key
 		}
+		names = append(names, name)
 	}
 	return names
 }
 
-func (a *App) isOptionRunning(opt *Option) bool {
-	if a.dockerStatus == nil {
+// isExternal reports whether a network or volume definition is managed
+// outside of Compose.
+func isExternal(res *yaml.Node) bool {
+	external := mappingNode(res, "external")
+	if external == nil {
 		return false
 	}
-
-	resolved, err := resolveOption(opt)
-	if err != nil {
-		return false
-	}
-
-	// Check based on the option's category
-	for _, name := range extractContainerNames(resolved) {
-		if a.dockerStatus.IsContainerRunning(name) {
-			return true
-		}
-	}
-	for _, name := range extractNetworkNames(resolved) {
-		if a.dockerStatus.IsNetworkExists(name) {
-			return true
-		}
-	}
-	for _, name := range extractVolumeNames(resolved) {
-		if a.dockerStatus.IsVolumeExists(name) {
-			return true
-		}
-	}
-
-	return false
+	return external.Kind == yaml.MappingNode || external.Value == "true"
 }
 
-// --- Docker Compose execution ---
-
-type logWriter struct {
-	app  *tview.Application
-	view *tview.TextView
-	ansi io.Writer
+func extractNetworkNames(resolved *yaml.Node, projectName string) []string {
+	return extractResourceNames(resolved, "networks", projectName)
 }
 
-func (w *logWriter) Write(p []byte) (n int, err error) {
-	w.app.QueueUpdateDraw(func() {
-		w.ansi.Write(p)
-		w.view.ScrollToEnd()
-	})
-	return len(p), nil
+func extractVolumeNames(resolved *yaml.Node, projectName string) []string {
+	return extractResourceNames(resolved, "volumes", projectName)
 }
 
-func (a *App) runDockerCompose(composeData map[string]interface{}, args ...string) {
-	yamlBytes, err := yaml.Marshal(composeData)
+// --- Docker Compose execution ---
+
+// writeComposeFile renders composeData, with config hash labels, into a
+// temporary compose file and returns its path. The caller is responsible
+// for removing it.
+func writeComposeFile(composeData *yaml.Node) (string, error) {
+	yamlStr, err := renderYAML(withConfigHashes(composeData))
 	if err != nil {
-		return
+		return "", err
 	}
 
 	tmpFile, err := os.CreateTemp("", "lazyrmss-compose-*.yaml")
 	if err != nil {
-		return
+		return "", err
 	}
 	tmpPath := tmpFile.Name()
 
-	if _, err := tmpFile.Write(yamlBytes); err != nil {
+	if _, err := tmpFile.WriteString(yamlStr); err != nil {
 		tmpFile.Close()
 		os.Remove(tmpPath)
-		return
+		return "", err
 	}
 	tmpFile.Close()
+	return tmpPath, nil
+}
 
-	cmdStr := strings.Join(args, " ")
-	a.logView.Clear()
-	fmt.Fprintf(a.logView, "[yellow]$ docker compose %s[-]\n", cmdStr)
-
-	cmdArgs := append([]string{"compose", "-f", tmpPath}, args...)
-	cmd := exec.Command("docker", cmdArgs...)
-
-	writer := &logWriter{
-		app:  a.app,
-		view: a.logView,
-		ansi: tview.ANSIWriter(a.logView),
+// runDockerCompose runs a compose command for p against composeData as a
+// background job.
+func (a *App) runDockerCompose(p project, composeData *yaml.Node, args ...string) {
+	tmpPath, err := writeComposeFile(composeData)
+	a.diagnostics.check("compose", "", err)
+	if err != nil {
+		return
 	}
-	cmd.Stdout = writer
-	cmd.Stderr = writer
 
-	go func() {
-		err := cmd.Run()
+	command := fmt.Sprintf("docker compose -p %s %s", p.Name, strings.Join(args, " "))
+	argv := append([]string{"docker"}, composeArgs(p, tmpPath, args...)...)
+	a.startJob(command, argv, func(err error) {
 		os.Remove(tmpPath)
-		a.app.QueueUpdateDraw(func() {
-			if err != nil {
-				fmt.Fprintf(a.logView, "\n[red]✗ %v[-]\n", err)
-			} else {
-				fmt.Fprintf(a.logView, "\n[green]✓ Done[-]\n")
-			}
-			a.logView.ScrollToEnd()
-		})
+		if services, ok := upServices(args); ok && err == nil {
+			a.diagnostics.check("state", appliedComposePath(p.Name), recordApplied(p.Name, composeData, services))
+		}
 		a.refreshDockerStatus()
-	}()
+	})
 }
 
-func (a *App) dockerComposeGlobal(args ...string) {
-	global, err := a.buildGlobalCompose()
-	if err != nil {
-		return
-	}
-	a.runDockerCompose(global, args...)
+// upValueFlags are the flags of docker compose up that take a separate
+// value.
+var upValueFlags = map[string]bool{
+	"--attach":         true,
+	"--exit-code-from": true,
+	"--no-attach":      true,
+	"--pull":           true,
+	"--scale":          true,
+	"--timeout":        true,
+	"-t":               true,
+	"--wait-timeout":   true,
 }
 
-func (a *App) runDockerDirect(targets []string, args ...string) {
-	cmdArgs := append(args, targets...)
-	cmdStr := strings.Join(cmdArgs, " ")
-	a.logView.Clear()
-	fmt.Fprintf(a.logView, "[yellow]$ docker %s[-]\n", cmdStr)
-
-	cmd := exec.Command("docker", cmdArgs...)
-
-	writer := &logWriter{
-		app:  a.app,
-		view: a.logView,
-		ansi: tview.ANSIWriter(a.logView),
+// upServices reports whether args run docker compose up and, if so, which
+// services it targets; none means the whole composition.
+func upServices(args []string) ([]string, bool) {
+	if len(args) == 0 || args[0] != "up" {
+		return nil, false
+	}
+	var services []string
+	for i := 1; i < len(args); i++ {
+		switch arg := args[i]; {
+		case upValueFlags[arg]:
+			i++
+		case !strings.HasPrefix(arg, "-"):
+			services = append(services, arg)
+		}
 	}
-	cmd.Stdout = writer
-	cmd.Stderr = writer
-
-	go func() {
-		err := cmd.Run()
-		a.app.QueueUpdateDraw(func() {
-			if err != nil {
-				fmt.Fprintf(a.logView, "\n[red]✗ %v[-]\n", err)
-			} else {
-				fmt.Fprintf(a.logView, "\n[green]✓ Done[-]\n")
-			}
-			a.logView.ScrollToEnd()
-		})
-		a.refreshDockerStatus()
-	}()
+	return services, true
 }
 
-func (a *App) dockerDirectSingle(args ...string) {
-	opt := a.getSelectedOption()
-	if opt == nil {
-		return
-	}
-	resolved, err := resolveOption(opt)
+// dockerComposeProject runs a compose command for the project of the
+// active tab.
+func (a *App) dockerComposeProject(args ...string) {
+	p := a.activeProject()
+	compose, err := a.buildCompose(p.Categories)
 	if err != nil {
+		a.diagnostics.report("compose", "", err)
 		return
 	}
-	names := extractContainerNames(resolved)
-	if len(names) == 0 {
-		return
-	}
-	a.runDockerDirect(names, args...)
+	a.runDockerCompose(p, compose, args...)
 }
 
-func extractImageNames(resolved map[string]interface{}) []string {
-	services, ok := resolved["services"].(map[string]interface{})
-	if !ok {
-		return nil
-	}
-	var names []string
-	for _, svcVal := range services {
-		svcMap, ok := svcVal.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		if img, ok := svcMap["image"].(string); ok && img != "" {
-			names = append(names, img)
-		}
-	}
-	return names
-}
-
-func (a *App) dockerPullSingle() {
-	opt := a.getSelectedOption()
-	if opt == nil {
+// dockerComposeSingle runs a compose command for the service keys of opt
+// only, in the project opt belongs to.
+func (a *App) dockerComposeSingle(opt *Option, args ...string) {
+	resolved, err := resolveOption(opt, a.diagnostics)
+	if err != nil {
 		return
 	}
-	resolved, err := resolveOption(opt)
-	if err != nil {
+	keys := serviceKeys(resolved)
+	if len(keys) == 0 {
 		return
 	}
-	images := extractImageNames(resolved)
-	if len(images) == 0 {
+	p := a.optionProject(opt)
+	compose, err := a.buildCompose(p.Categories)
+	if err != nil {
+		a.diagnostics.report("compose", "", err)
 		return
 	}
-	a.runDockerDirect(images, "pull")
+	a.runDockerCompose(p, compose, append(append([]string{}, args...), keys...)...)
 }
 
 func (a *App) refreshDockerStatus() {
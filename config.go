@@ -12,12 +12,26 @@ import (
 type Config struct {
 	ResourcesDir string `yaml:"resources_dir"`
 	PollInterval int    `yaml:"poll_interval"`
+	LogTail      int    `yaml:"log_tail"`
+	// Shells maps "category/service" or a service name to the shell used
+	// for interactive sessions, tried before bash and sh.
+	Shells map[string]string `yaml:"shells"`
+	// EmitDependsOn adds compose depends_on entries for dependencies
+	// declared between services in their manifests.
+	EmitDependsOn bool `yaml:"emit_depends_on"`
+	// ProjectName is passed to docker compose with -p.
+	ProjectName string `yaml:"project_name"`
+	// ProjectPerCategory makes every category its own compose project,
+	// named <project_name>-<category>.
+	ProjectPerCategory bool `yaml:"project_per_category"`
 }
 
 func DefaultConfig() *Config {
 	return &Config{
 		ResourcesDir: "$XDG_CONFIG_HOME/rmss",
 		PollInterval: 3,
+		LogTail:      200,
+		ProjectName:  defaultProjectName,
 	}
 }
 
@@ -66,6 +80,12 @@ func dataDir() string {
 	return filepath.Join(home, ".local", "share", "lazyrmss")
 }
 
+// appliedComposePath holds the composition of a project last brought up
+// with "up".
+func appliedComposePath(projectName string) string {
+	return filepath.Join(dataDir(), "applied-"+projectName+".yaml")
+}
+
 func (a *App) stateFilePath() string {
 	return filepath.Join(dataDir(), "state.yaml")
 }
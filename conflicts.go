@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxPortRange bounds the expansion of published port ranges.
+const maxPortRange = 1024
+
+// conflict is a clash between enabled services that buildGlobalCompose
+// would merge silently or Docker would reject at runtime.
+type conflict struct {
+	Kind    string // port, container, service, network or volume
+	Message string
+}
+
+// claims collects who uses a name, in first-seen order, ignoring repeats.
+type claims struct {
+	order  []string
+	owners map[string][]string
+}
+
+func newClaims() *claims {
+	return &claims{owners: make(map[string][]string)}
+}
+
+func (c *claims) add(name, owner string) {
+	owners, ok := c.owners[name]
+	if !ok {
+		c.order = append(c.order, name)
+	}
+	for _, o := range owners {
+		if o == owner {
+			return
+		}
+	}
+	c.owners[name] = append(owners, owner)
+}
+
+// shared returns the names claimed by more than one owner.
+func (c *claims) shared() []string {
+	var names []string
+	for _, name := range c.order {
+		if len(c.owners[name]) > 1 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// analyseConflicts checks the enabled services against each other,
+// resolving them through r. Host ports and container names are shared by
+// all projects on the host; service keys and top-level networks and
+// volumes only clash within one compose project.
+func (a *App) analyseConflicts(r *resolutions) []conflict {
+	containers := newClaims()
+	ports := newClaims()
+	var projectConflicts []conflict
+
+	for _, p := range a.projects() {
+		serviceKeys := newClaims()
+		networks := make(map[string]map[string][]string) // name -> definition -> options
+		volumes := make(map[string]map[string][]string)
+		var networkOrder, volumeOrder []string
+
+		for _, cat := range p.Categories {
+			for _, opt := range a.options[cat.Name] {
+				if !opt.Enabled {
+					continue
+				}
+				resolved, err := r.resolve(opt)
+				if err != nil {
+					continue
+				}
+				// Interpolation modifies nodes in place.
+				resolved = cloneNode(resolved)
+				a.interpolateOption(opt, resolved, nil)
+				ref := optionRef(opt)
+
+				services := mappingNode(resolved, "services")
+				for _, svc := range extractServiceRefs(resolved) {
+					owner := fmt.Sprintf("%s (%s)", ref, svc.Key)
+					serviceKeys.add(svc.Key, ref)
+					if svc.ContainerName != "" {
+						containers.add(svc.ContainerName, owner)
+					}
+					for _, port := range publishedPorts(mappingNode(mappingNode(services, svc.Key), "ports")) {
+						ports.add(port, owner)
+					}
+				}
+
+				networkOrder = collectDefinitions(networks, networkOrder, mappingNode(resolved, "networks"), ref)
+				volumeOrder = collectDefinitions(volumes, volumeOrder, mappingNode(resolved, "volumes"), ref)
+			}
+		}
+
+		for _, key := range serviceKeys.shared() {
+			projectConflicts = append(projectConflicts, conflict{"service", fmt.Sprintf("service key %q is defined by %s and gets merged", key, strings.Join(serviceKeys.owners[key], " and "))})
+		}
+		for _, name := range networkOrder {
+			if defs := networks[name]; len(defs) > 1 {
+				projectConflicts = append(projectConflicts, conflict{"network", fmt.Sprintf("network %q is defined differently by %s", name, formatDefinitionOwners(defs))})
+			}
+		}
+		for _, name := range volumeOrder {
+			if defs := volumes[name]; len(defs) > 1 {
+				projectConflicts = append(projectConflicts, conflict{"volume", fmt.Sprintf("volume %q is defined differently by %s", name, formatDefinitionOwners(defs))})
+			}
+		}
+	}
+
+	// A port bound on one address also clashes with the same port bound on
+	// all addresses.
+	for _, name := range ports.order {
+		if i := strings.LastIndex(name, ":"); i >= 0 {
+			for _, owner := range ports.owners[name[i+1:]] {
+				ports.add(name, owner)
+			}
+		}
+	}
+
+	var result []conflict
+	for _, port := range ports.shared() {
+		result = append(result, conflict{"port", fmt.Sprintf("host port %s is published by %s", port, strings.Join(ports.owners[port], " and "))})
+	}
+	for _, name := range containers.shared() {
+		result = append(result, conflict{"container", fmt.Sprintf("container name %q is used by %s", name, strings.Join(containers.owners[name], " and "))})
+	}
+	return append(result, projectConflicts...)
+}
+
+// collectDefinitions records, for each top-level network or volume in
+// section, which options define it in which way.
+func collectDefinitions(defs map[string]map[string][]string, order []string, section *yaml.Node, ref string) []string {
+	if section == nil || section.Kind != yaml.MappingNode {
+		return order
+	}
+	for i := 0; i+1 < len(section.Content); i += 2 {
+		name := section.Content[i].Value
+		if _, ok := defs[name]; !ok {
+			defs[name] = make(map[string][]string)
+			order = append(order, name)
+		}
+		key := canonicalDefinition(section.Content[i+1])
+		defs[name][key] = append(defs[name][key], ref)
+	}
+	return order
+}
+
+// canonicalDefinition renders a definition so that equal settings compare
+// equal regardless of key order; an empty definition equals null.
+func canonicalDefinition(n *yaml.Node) string {
+	var v interface{}
+	if err := n.Decode(&v); err != nil || v == nil {
+		return "{}"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+func formatDefinitionOwners(defs map[string][]string) string {
+	var groups []string
+	for _, refs := range defs {
+		groups = append(groups, strings.Join(refs, ", "))
+	}
+	sort.Strings(groups)
+	return strings.Join(groups, " vs ")
+}
+
+// publishedPorts returns the host ports a ports list binds, as
+// "[ip:]port/protocol". Ports left to Docker or set by interpolation are
+// skipped.
+func publishedPorts(ports *yaml.Node) []string {
+	if ports == nil || ports.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var result []string
+	for _, item := range ports.Content {
+		var ip, published, proto string
+		switch item.Kind {
+		case yaml.ScalarNode:
+			ip, published, proto = parsePortSpec(item.Value)
+		case yaml.MappingNode:
+			ip, published, proto = mappingValue(item, "host_ip"), mappingValue(item, "published"), mappingValue(item, "protocol")
+		}
+		if proto == "" {
+			proto = "tcp"
+		}
+		if ip == "0.0.0.0" || ip == "::" {
+			ip = ""
+		}
+		for _, port := range expandPortRange(published) {
+			if ip != "" {
+				port = ip + ":" + port
+			}
+			result = append(result, port+"/"+proto)
+		}
+	}
+	return result
+}
+
+// parsePortSpec splits the short port syntax "[ip:][host:]container[/proto]".
+func parsePortSpec(spec string) (ip, published, proto string) {
+	spec, proto, _ = strings.Cut(spec, "/")
+
+	if strings.HasPrefix(spec, "[") {
+		end := strings.Index(spec, "]")
+		if end < 0 {
+			return "", "", proto
+		}
+		ip = spec[1:end]
+		spec = strings.TrimPrefix(spec[end+1:], ":")
+		host, _, ok := strings.Cut(spec, ":")
+		if !ok {
+			return ip, "", proto
+		}
+		return ip, host, proto
+	}
+
+	parts := strings.Split(spec, ":")
+	switch len(parts) {
+	case 1:
+		return "", "", proto
+	case 2:
+		return "", parts[0], proto
+	default:
+		return strings.Join(parts[:len(parts)-2], ":"), parts[len(parts)-2], proto
+	}
+}
+
+// expandPortRange turns "8000" or "8000-8002" into individual ports.
+func expandPortRange(published string) []string {
+	if published == "" || strings.Contains(published, "$") {
+		return nil
+	}
+	lo, hi, isRange := strings.Cut(published, "-")
+	start, err := strconv.Atoi(lo)
+	if err != nil {
+		return nil
+	}
+	end := start
+	if isRange {
+		if end, err = strconv.Atoi(hi); err != nil || end < start || end-start > maxPortRange {
+			return nil
+		}
+	}
+	ports := make([]string, 0, end-start+1)
+	for p := start; p <= end; p++ {
+		ports = append(ports, strconv.Itoa(p))
+	}
+	return ports
+}
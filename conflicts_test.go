@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePortSpec(t *testing.T) {
+	tests := []struct {
+		spec                 string
+		ip, published, proto string
+	}{
+		{"80", "", "", ""},
+		{"80/udp", "", "", "udp"},
+		{"8080:80", "", "8080", ""},
+		{"8080:80/udp", "", "8080", "udp"},
+		{"8000-8002:80-82", "", "8000-8002", ""},
+		{"127.0.0.1:8080:80", "127.0.0.1", "8080", ""},
+		{"127.0.0.1::80", "127.0.0.1", "", ""},
+		{"127.0.0.1:53:53/udp", "127.0.0.1", "53", "udp"},
+		{"[::1]:8080:80", "::1", "8080", ""},
+		{"[::1]:80", "::1", "", ""},
+		{"[::1]:53:53/udp", "::1", "53", "udp"},
+		{"[::1", "", "", ""},
+	}
+	for _, tt := range tests {
+		ip, published, proto := parsePortSpec(tt.spec)
+		if ip != tt.ip || published != tt.published || proto != tt.proto {
+			t.Errorf("parsePortSpec(%q) = %q, %q, %q, want %q, %q, %q", tt.spec, ip, published, proto, tt.ip, tt.published, tt.proto)
+		}
+	}
+}
+
+func TestPublishedPorts(t *testing.T) {
+	tests := []struct {
+		name  string
+		ports string
+		want  []string
+	}{
+		{"container port only", `["80"]`, nil},
+		{"short syntax", `["8080:80", "443:443"]`, []string{"8080/tcp", "443/tcp"}},
+		{"udp", `["53:53/udp", "53:53"]`, []string{"53/udp", "53/tcp"}},
+		{"range", `["8000-8002:80-82"]`, []string{"8000/tcp", "8001/tcp", "8002/tcp"}},
+		{"reversed range", `["8002-8000:80"]`, nil},
+		{"ip bound", `["127.0.0.1:8080:80", "[::1]:9090:90/udp"]`, []string{"127.0.0.1:8080/tcp", "::1:9090/udp"}},
+		{"all addresses", `["0.0.0.0:8080:80", "[::]:9090:90"]`, []string{"8080/tcp", "9090/tcp"}},
+		{"variable", `["${PORT}:80", "${PORT:-8080}:80"]`, nil},
+		{
+			"long syntax",
+			`[{target: 80, published: "8080"}, {target: 53, published: 53, protocol: udp, host_ip: 127.0.0.1}, {target: 90}]`,
+			[]string{"8080/tcp", "127.0.0.1:53/udp"},
+		},
+		{"long syntax range", `[{target: 80, published: "8000-8001"}]`, []string{"8000/tcp", "8001/tcp"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := publishedPorts(parseTestNode(t, tt.ports))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got := publishedPorts(nil); got != nil {
+		t.Errorf("publishedPorts(nil) = %q", got)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// optionRef is the "<category>/<service>" reference used in manifests.
+func optionRef(opt *Option) string {
+	return opt.Category + "/" + opt.Name
+}
+
+// dependencies returns every service opt transitively depends on, in
+// dependency order, and the references that match no known service.
+func (a *App) dependencies(opt *Option) ([]*Option, []string) {
+	var deps []*Option
+	var missing []string
+	seen := map[*Option]bool{opt: true}
+
+	var visit func(o *Option)
+	visit = func(o *Option) {
+		for _, ref := range o.Manifest.DependsOn {
+			dep, err := a.findOption(ref)
+			if err != nil {
+				missing = append(missing, ref)
+				continue
+			}
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			visit(dep)
+			deps = append(deps, dep)
+		}
+	}
+	visit(opt)
+	return deps, missing
+}
+
+// disabledDependencies returns the dependencies of opt that are not enabled.
+func (a *App) disabledDependencies(opt *Option) []*Option {
+	deps, _ := a.dependencies(opt)
+	var disabled []*Option
+	for _, dep := range deps {
+		if !dep.Enabled {
+			disabled = append(disabled, dep)
+		}
+	}
+	return disabled
+}
+
+// enabledDependents returns the enabled services that depend on opt,
+// directly or transitively.
+func (a *App) enabledDependents(opt *Option) []*Option {
+	var result []*Option
+	for _, cat := range a.categories {
+		for _, other := range a.options[cat.Name] {
+			if other == opt || !other.Enabled {
+				continue
+			}
+			deps, _ := a.dependencies(other)
+			for _, dep := range deps {
+				if dep == opt {
+					result = append(result, other)
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+func formatOptionRefs(opts []*Option) string {
+	refs := make([]string, len(opts))
+	for i, o := range opts {
+		refs[i] = optionRef(o)
+	}
+	return strings.Join(refs, ", ")
+}
+
+// --- Compose depends_on ---
+
+// serviceKeys returns the compose service keys defined by a resolved option.
+func serviceKeys(resolved *yaml.Node) []string {
+	var keys []string
+	for _, ref := range extractServiceRefs(resolved) {
+		keys = append(keys, ref.Key)
+	}
+	return keys
+}
+
+// addComposeDependsOn makes every service in services depend on each of
+// targets, keeping existing entries in either the list or the map form.
+func addComposeDependsOn(services *yaml.Node, keys, targets []string) {
+	for _, key := range keys {
+		svc := mappingNode(services, key)
+		if svc == nil || svc.Kind != yaml.MappingNode {
+			continue
+		}
+		existing := mappingNode(svc, "depends_on")
+		if existing != nil && existing.Kind == yaml.MappingNode {
+			for _, t := range targets {
+				if mappingIndex(existing, t) < 0 && t != key {
+					condition := newMappingNode()
+					condition.Content = append(condition.Content, scalarNode("condition"), scalarNode("service_started"))
+					existing.Content = append(existing.Content, scalarNode(t), condition)
+				}
+			}
+			continue
+		}
+
+		list := existing
+		if list == nil || list.Kind != yaml.SequenceNode {
+			list = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		}
+		present := make(map[string]bool)
+		for _, item := range list.Content {
+			present[item.Value] = true
+		}
+		for _, t := range targets {
+			if !present[t] && t != key {
+				list.Content = append(list.Content, scalarNode(t))
+				present[t] = true
+			}
+		}
+		if len(list.Content) > 0 && list != existing {
+			if idx := mappingIndex(svc, "depends_on"); idx >= 0 {
+				svc.Content[idx+1] = list
+			} else {
+				svc.Content = append(svc.Content, scalarNode("depends_on"), list)
+			}
+		}
+	}
+}
+
+// applyComposeDependsOn adds depends_on entries between the services of
+// the enabled options of categories, merged into global, according to
+// their manifests, resolving the options through r. An option that cannot
+// be resolved is skipped, as it is left out of global too; resolving it
+// has already reported the failure to the diagnostics log.
+func (a *App) applyComposeDependsOn(global *yaml.Node, categories []Category, r *resolutions) {
+	services := mappingNode(global, "services")
+	if services == nil {
+		return
+	}
+
+	for _, cat := range categories {
+		for _, opt := range a.options[cat.Name] {
+			if !opt.Enabled || len(opt.Manifest.DependsOn) == 0 {
+				continue
+			}
+			resolved, err := r.resolve(opt)
+			if err != nil {
+				continue
+			}
+
+			var targets []string
+			for _, ref := range opt.Manifest.DependsOn {
+				dep, err := a.findOption(ref)
+				if err != nil || !dep.Enabled {
+					continue
+				}
+				depResolved, err := r.resolve(dep)
+				if err != nil {
+					continue
+				}
+				// A dependency in another compose project cannot be
+				// referenced.
+				for _, key := range serviceKeys(depResolved) {
+					if mappingIndex(services, key) >= 0 {
+						targets = append(targets, key)
+					}
+				}
+			}
+			addComposeDependsOn(services, serviceKeys(resolved), targets)
+		}
+	}
+}
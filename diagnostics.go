@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// diagnostic is a failure lazyrmss worked around by skipping something,
+// such as an addon whose YAML does not parse and is left out of the merge.
+type diagnostic struct {
+	// Kind is base, addon, category, service, manifest, env, compose,
+	// docker, clipboard or state.
+	Kind string
+	File string
+	Err  error
+	Time time.Time
+}
+
+func (d diagnostic) String() string {
+	if d.File == "" {
+		return fmt.Sprintf("%s: %v", d.Kind, d.Err)
+	}
+	return fmt.Sprintf("%s %s: %v", d.Kind, filepath.Base(d.File), d.Err)
+}
+
+// diagnosticLog collects diagnostics from the whole pipeline. A failure
+// that repeats, like a broken addon resolved on every redraw, is kept once,
+// and it is dropped when the same kind of operation on the same file
+// succeeds again.
+type diagnosticLog struct {
+	mu       sync.Mutex
+	entries  []diagnostic
+	onReport func(diagnostic)
+}
+
+// report records a failure and passes it to onReport unless it is already
+// known.
+func (l *diagnosticLog) report(kind, file string, err error) {
+	l.mu.Lock()
+	if i := l.index(kind, file); i >= 0 {
+		if l.entries[i].Err.Error() == err.Error() {
+			l.mu.Unlock()
+			return
+		}
+		l.entries = append(l.entries[:i], l.entries[i+1:]...)
+	}
+	d := diagnostic{Kind: kind, File: file, Err: err, Time: time.Now()}
+	l.entries = append(l.entries, d)
+	onReport := l.onReport
+	l.mu.Unlock()
+
+	if onReport != nil {
+		onReport(d)
+	}
+}
+
+// resolve drops the diagnostic of kind for file after a success.
+func (l *diagnosticLog) resolve(kind, file string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if i := l.index(kind, file); i >= 0 {
+		l.entries = append(l.entries[:i], l.entries[i+1:]...)
+	}
+}
+
+// check reports err, or resolves the diagnostic if err is nil.
+func (l *diagnosticLog) check(kind, file string, err error) {
+	if err != nil {
+		l.report(kind, file, err)
+	} else {
+		l.resolve(kind, file)
+	}
+}
+
+func (l *diagnosticLog) index(kind, file string) int {
+	for i, d := range l.entries {
+		if d.Kind == kind && d.File == file {
+			return i
+		}
+	}
+	return -1
+}
+
+func (l *diagnosticLog) list() []diagnostic {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]diagnostic(nil), l.entries...)
+}
+
+func (l *diagnosticLog) setHandler(onReport func(diagnostic)) {
+	l.mu.Lock()
+	l.onReport = onReport
+	l.mu.Unlock()
+}
+
+// watchDiagnostics shows every new diagnostic in the status bar.
+func (a *App) watchDiagnostics() {
+	a.diagnostics.setHandler(func(d diagnostic) {
+		// Reports come from the UI goroutine as well as from jobs.
+		go a.app.QueueUpdateDraw(func() {
+			a.notify("red", "✗ "+d.String()+" (! for problems)")
+		})
+	})
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+type diffOp struct {
+	Kind byte // ' ' unchanged, '-' removed, '+' added
+	Text string
+}
+
+// diffLines returns the line edits turning old into new, based on their
+// longest common subsequence. Removals are listed before additions.
+func diffLines(old, new []string) []diffOp {
+	n, m := len(old), len(new)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && old[i] == new[j]:
+			ops = append(ops, diffOp{' ', old[i]})
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', old[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', new[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+func hasChanges(ops []diffOp) bool {
+	for _, op := range ops {
+		if op.Kind != ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+// unifiedDiff formats ops as a unified diff with diffContext lines of
+// context. It is empty when nothing changed.
+func unifiedDiff(oldName, newName string, ops []diffOp) string {
+	if !hasChanges(ops) {
+		return ""
+	}
+
+	// Line numbers in old and new before each op.
+	oldPos := make([]int, len(ops)+1)
+	newPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if op.Kind != '+' {
+			oldPos[i+1]++
+		}
+		if op.Kind != '-' {
+			newPos[i+1]++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+
+	i := 0
+	for i < len(ops) {
+		for i < len(ops) && ops[i].Kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		start := max(i-diffContext, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].Kind == ' ' {
+				next++
+			}
+			if next < len(ops) && next-end <= 2*diffContext {
+				end = next
+				continue
+			}
+			end = min(end+diffContext, len(ops))
+			break
+		}
+
+		oldCount, newCount := oldPos[end]-oldPos[start], newPos[end]-newPos[start]
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldPos[start], oldCount), hunkRange(newPos[start], newCount))
+		for _, op := range ops[start:end] {
+			b.WriteByte(op.Kind)
+			b.WriteString(op.Text)
+			b.WriteByte('\n')
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange formats a hunk header range; pos is the number of lines before
+// the hunk.
+func hunkRange(pos, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", pos)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", pos+1)
+	}
+	return fmt.Sprintf("%d,%d", pos+1, count)
+}
+
+// sideBySideDiff renders ops in two columns of the given total width as
+// tview markup. Runs of removals and additions are paired row by row.
+func sideBySideDiff(ops []diffOp, width int) string {
+	col := max((width-3)/2, 10)
+	cell := func(text, color string) string {
+		runes := []rune(strings.ReplaceAll(text, "\t", "    "))
+		if len(runes) > col {
+			runes = runes[:col]
+		}
+		padded := string(runes) + strings.Repeat(" ", col-len(runes))
+		if color == "" {
+			return tview.Escape(padded)
+		}
+		return fmt.Sprintf("[%s]%s[-]", color, tview.Escape(padded))
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(ops); {
+		if ops[i].Kind == ' ' {
+			fmt.Fprintf(&b, "%s [gray]│[-] %s\n", cell(ops[i].Text, ""), cell(ops[i].Text, ""))
+			i++
+			continue
+		}
+
+		var removed, added []string
+		for ; i < len(ops) && ops[i].Kind == '-'; i++ {
+			removed = append(removed, ops[i].Text)
+		}
+		for ; i < len(ops) && ops[i].Kind == '+'; i++ {
+			added = append(added, ops[i].Text)
+		}
+		for row := 0; row < max(len(removed), len(added)); row++ {
+			left, right := cell("", ""), cell("", "")
+			if row < len(removed) {
+				left = cell(removed[row], "red")
+			}
+			if row < len(added) {
+				right = cell(added[row], "green")
+			}
+			fmt.Fprintf(&b, "%s [gray]│[-] %s\n", left, right)
+		}
+	}
+	return b.String()
+}
+
+// --- Diff preview ---
+
+const (
+	diffOff = iota
+	diffUnified
+	diffSideBySide
+)
+
+// cycleDiffMode switches the preview between plain, unified diff and
+// side-by-side diff.
+func (a *App) cycleDiffMode() {
+	a.diffMode = (a.diffMode + 1) % 3
+	if a.diffMode != diffOff && a.annotate.enabled {
+		a.annotate.enabled = false
+		a.previewView.Highlight()
+	}
+	a.updatePreview()
+}
+
+// showDiffPreview compares, in the addons panel, the selected service with
+// and without the highlighted addon toggled, and in the options panel the
+// composition last brought up against the one U would apply now.
+func (a *App) showDiffPreview(opt *Option) {
+	var oldName, newName, oldText, newText string
+
+	if a.currentPanelIdx == 1 {
+		addon := a.getSelectedAddon()
+		if addon == nil {
+			a.previewView.SetText("[white]No addon selected[-]")
+			return
+		}
+		toggled, err := opt.withAddonToggled(addon.Name)
+		if err != nil {
+			a.previewView.SetText(fmt.Sprintf("[red]Cannot toggle %s: %s[-]", tview.Escape(addon.Name), tview.Escape(err.Error())))
+			return
+		}
+		sign := "+"
+		if opt.ActiveAddons[addon.Name] {
+			sign = "-"
+		}
+		oldName, newName = opt.Name, fmt.Sprintf("%s %s%s", opt.Name, sign, addon.Name)
+		if oldText, err = renderOption(opt, a.diagnostics); err == nil {
+			newText, err = renderOption(toggled, a.diagnostics)
+		}
+		if err != nil {
+			a.previewView.SetText(fmt.Sprintf("[red]Error: %v[-]", err))
+			return
+		}
+	} else {
+		p := a.activeProject()
+		applied, err := loadAppliedCompose(p.Name)
+		if err != nil {
+			a.previewView.SetText("[gray]Nothing applied yet. The composition is recorded each time it is brought up.[-]")
+			return
+		}
+		compose, err := a.buildCompose(p.Categories)
+		if err == nil {
+			newText, err = renderYAML(compose)
+		}
+		if err != nil {
+			a.previewView.SetText(fmt.Sprintf("[red]Error: %v[-]", err))
+			return
+		}
+		oldName, newName, oldText = "applied", "current", applied
+	}
+
+	mode := "unified"
+	if a.diffMode == diffSideBySide {
+		mode = "side by side"
+	}
+	a.previewView.SetTitle(tview.Escape(fmt.Sprintf(" %s → %s (%s) ", oldName, newName, mode)))
+
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+	if !hasChanges(ops) {
+		a.previewView.SetText("[gray]No changes[-]")
+		return
+	}
+	if a.diffMode == diffSideBySide {
+		_, _, width, _ := a.previewView.GetInnerRect()
+		a.previewView.SetText(sideBySideDiff(ops, width))
+	} else {
+		a.previewView.SetText(highlightCode(unifiedDiff(oldName, newName, ops), "diff"))
+	}
+	a.previewView.ScrollToBeginning()
+}
+
+// withAddonToggled returns a copy of opt with name switched on or off,
+// following the same rules as toggling it in the UI.
+func (opt *Option) withAddonToggled(name string) (*Option, error) {
+	clone := *opt
+	clone.ActiveAddons = make(map[string]bool, len(opt.ActiveAddons))
+	for k, v := range opt.ActiveAddons {
+		clone.ActiveAddons[k] = v
+	}
+
+	var err error
+	if opt.ActiveAddons[name] {
+		_, err = clone.disableAddon(name)
+	} else {
+		_, err = clone.enableAddon(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
+
+func renderOption(opt *Option, diags *diagnosticLog) (string, error) {
+	resolved, err := resolveOption(opt, diags)
+	if err != nil {
+		return "", err
+	}
+	return renderYAML(resolved)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numberedLines returns "l1" to "ln", with the lines listed in changes
+// replaced.
+func numberedLines(n int, changes map[int]string) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("l%d", i+1)
+		if text, ok := changes[i+1]; ok {
+			lines[i] = text
+		}
+	}
+	return lines
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new []string
+		want     string
+	}{
+		{
+			name: "no change",
+			old:  numberedLines(20, nil),
+			new:  numberedLines(20, nil),
+			want: "",
+		},
+		{
+			name: "change at the start",
+			old:  numberedLines(20, nil),
+			new:  numberedLines(20, map[int]string{1: "X"}),
+			want: `@@ -1,4 +1,4 @@
+-l1
++X
+ l2
+ l3
+ l4
+`,
+		},
+		{
+			name: "change at the end",
+			old:  numberedLines(20, nil),
+			new:  numberedLines(20, map[int]string{20: "X"}),
+			want: `@@ -17,4 +17,4 @@
+ l17
+ l18
+ l19
+-l20
++X
+`,
+		},
+		{
+			name: "changes within twice the context share a hunk",
+			old:  numberedLines(20, nil),
+			new:  numberedLines(20, map[int]string{5: "X", 12: "Y"}),
+			want: `@@ -2,14 +2,14 @@
+ l2
+ l3
+ l4
+-l5
++X
+ l6
+ l7
+ l8
+ l9
+ l10
+ l11
+-l12
++Y
+ l13
+ l14
+ l15
+`,
+		},
+		{
+			name: "changes beyond twice the context get separate hunks",
+			old:  numberedLines(20, nil),
+			new:  numberedLines(20, map[int]string{5: "X", 13: "Y"}),
+			want: `@@ -2,7 +2,7 @@
+ l2
+ l3
+ l4
+-l5
++X
+ l6
+ l7
+ l8
+@@ -10,7 +10,7 @@
+ l10
+ l11
+ l12
+-l13
++Y
+ l14
+ l15
+ l16
+`,
+		},
+		{
+			name: "added to an empty file",
+			old:  nil,
+			new:  []string{"a"},
+			want: `@@ -0,0 +1 @@
++a
+`,
+		},
+		{
+			name: "emptied",
+			old:  []string{"a"},
+			new:  nil,
+			want: `@@ -1 +0,0 @@
+-a
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedDiff("old", "new", diffLines(tt.old, tt.new))
+			want := tt.want
+			if want != "" {
+				want = "--- old\n+++ new\n" + want
+			}
+			if got != want {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesOrder(t *testing.T) {
+	ops := diffLines([]string{"a", "b", "c"}, []string{"a", "B", "c", "d"})
+	var got []string
+	for _, op := range ops {
+		got = append(got, string(op.Kind)+op.Text)
+	}
+	if want := " a,-b,+B, c,+d"; strings.Join(got, ",") != want {
+		t.Errorf("got %s, want %s", strings.Join(got, ","), want)
+	}
+}
@@ -2,7 +2,8 @@ package main
 
 import (
 	"context"
-	"os/exec"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -10,106 +11,214 @@ import (
 
 // DockerStatus holds the live state of Docker resources, protected by a mutex.
 type DockerStatus struct {
-	mu sync.RWMutex
+	mu     sync.RWMutex
+	client *engineClient
 
-	RunningContainers map[string]bool
-	ExistingNetworks  map[string]bool
-	ExistingVolumes   map[string]bool
+	Containers map[string]ContainerInfo
+	Networks   map[string]NetworkInfo
+	Volumes    map[string]VolumeInfo
 }
 
-func queryRunningContainers(ctx context.Context) (map[string]bool, error) {
-	cmd := exec.CommandContext(ctx, "docker", "ps", "--format", "{{.Names}}")
-	out, err := cmd.Output()
-	if err != nil {
-		return nil, err
+func newDockerStatus(client *engineClient) *DockerStatus {
+	return &DockerStatus{
+		client:     client,
+		Containers: make(map[string]ContainerInfo),
+		Networks:   make(map[string]NetworkInfo),
+		Volumes:    make(map[string]VolumeInfo),
 	}
-	return parseLines(string(out)), nil
 }
 
-func queryExistingNetworks(ctx context.Context) (map[string]bool, error) {
-	cmd := exec.CommandContext(ctx, "docker", "network", "ls", "--format", "{{.Name}}")
-	out, err := cmd.Output()
-	if err != nil {
-		return nil, err
+// poll refreshes the full snapshot and reports whether anything changed.
+func (ds *DockerStatus) poll(ctx context.Context) bool {
+	if ds.client == nil {
+		return false
 	}
-	return parseLines(string(out)), nil
-}
-
-func queryExistingVolumes(ctx context.Context) (map[string]bool, error) {
-	cmd := exec.CommandContext(ctx, "docker", "volume", "ls", "--format", "{{.Name}}")
-	out, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
-	return parseLines(string(out)), nil
-}
-
-func parseLines(output string) map[string]bool {
-	result := make(map[string]bool)
-	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			result[line] = true
-		}
-	}
-	return result
-}
 
-func (ds *DockerStatus) poll(ctx context.Context) {
-	containers, errC := queryRunningContainers(ctx)
-	networks, errN := queryExistingNetworks(ctx)
-	volumes, errV := queryExistingVolumes(ctx)
+	containers, errC := ds.client.containers(ctx)
+	networks, errN := ds.client.networks(ctx)
+	volumes, errV := ds.client.volumes(ctx)
 
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
 
-	if errC == nil {
-		ds.RunningContainers = containers
+	changed := false
+	if errC == nil && !reflect.DeepEqual(ds.Containers, containers) {
+		ds.Containers = containers
+		changed = true
 	}
-	if errN == nil {
-		ds.ExistingNetworks = networks
+	if errN == nil && !reflect.DeepEqual(ds.Networks, networks) {
+		ds.Networks = networks
+		changed = true
 	}
-	if errV == nil {
-		ds.ExistingVolumes = volumes
+	if errV == nil && !reflect.DeepEqual(ds.Volumes, volumes) {
+		ds.Volumes = volumes
+		changed = true
 	}
+	return changed
 }
 
-// StartPolling launches a background goroutine that polls Docker status
-// at the given interval and calls refreshUI after each poll.
-func (ds *DockerStatus) StartPolling(ctx context.Context, interval time.Duration, refreshUI func()) {
+// StartWatching launches a background goroutine that keeps the status in
+// sync with the Docker events stream and calls refreshUI whenever a
+// relevant change is applied. While the stream is unavailable it falls back
+// to polling at the given interval, retrying the subscription each time.
+func (ds *DockerStatus) StartWatching(ctx context.Context, interval time.Duration, refreshUI func()) {
 	go func() {
-		// Initial poll before entering the ticker loop.
-		ds.poll(ctx)
-		refreshUI()
-
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
 		for {
+			// Resync the full snapshot, then subscribe from that point so
+			// nothing that happens in between is missed.
+			since := time.Now()
+			if ds.poll(ctx) {
+				refreshUI()
+			}
+			if ds.client != nil {
+				ds.client.events(ctx, since, func(ev engineEvent) {
+					if ds.applyEvent(ctx, ev) {
+						refreshUI()
+					}
+				})
+			}
+
 			select {
 			case <-ctx.Done():
 				return
-			case <-ticker.C:
-				ds.poll(ctx)
-				refreshUI()
+			case <-time.After(interval):
 			}
 		}
 	}()
 }
 
-func (ds *DockerStatus) IsContainerRunning(name string) bool {
+// applyEvent updates the snapshot incrementally for a single event and
+// reports whether anything visible changed.
+func (ds *DockerStatus) applyEvent(ctx context.Context, ev engineEvent) bool {
+	// health_status events carry the result in the action, e.g.
+	// "health_status: healthy".
+	action, _, _ := strings.Cut(ev.Action, ":")
+
+	switch ev.Type {
+	case "container":
+		switch action {
+		case "create", "start", "restart", "stop", "die", "kill", "pause", "unpause", "rename", "health_status":
+			info, ok, err := ds.client.containerByID(ctx, ev.Actor.ID)
+			if err != nil {
+				return false
+			}
+			return ds.updateContainer(ev.Actor.ID, info, ok)
+		case "destroy":
+			return ds.updateContainer(ev.Actor.ID, ContainerInfo{}, false)
+		}
+
+	case "network":
+		name := ev.Actor.Attributes["name"]
+		switch action {
+		case "create":
+			info, err := ds.client.network(ctx, ev.Actor.ID)
+			if err != nil {
+				return false
+			}
+			ds.mu.Lock()
+			defer ds.mu.Unlock()
+			ds.Networks[info.Name] = info
+			return true
+		case "destroy":
+			ds.mu.Lock()
+			defer ds.mu.Unlock()
+			if _, ok := ds.Networks[name]; !ok {
+				return false
+			}
+			delete(ds.Networks, name)
+			return true
+		}
+
+	case "volume":
+		// For volumes the actor ID is the volume name.
+		switch action {
+		case "create":
+			info, err := ds.client.volume(ctx, ev.Actor.ID)
+			if err != nil {
+				return false
+			}
+			ds.mu.Lock()
+			defer ds.mu.Unlock()
+			ds.Volumes[info.Name] = info
+			return true
+		case "destroy":
+			ds.mu.Lock()
+			defer ds.mu.Unlock()
+			if _, ok := ds.Volumes[ev.Actor.ID]; !ok {
+				return false
+			}
+			delete(ds.Volumes, ev.Actor.ID)
+			return true
+		}
+	}
+
+	return false
+}
+
+// updateContainer replaces every entry for the container id with info, or
+// removes them when exists is false. Renames are handled by dropping the
+// entry under the old name.
+func (ds *DockerStatus) updateContainer(id string, info ContainerInfo, exists bool) bool {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	changed := false
+	for name, existing := range ds.Containers {
+		if existing.ID != id {
+			continue
+		}
+		if exists && name == info.Name {
+			continue
+		}
+		delete(ds.Containers, name)
+		changed = true
+	}
+
+	if exists && !reflect.DeepEqual(ds.Containers[info.Name], info) {
+		ds.Containers[info.Name] = info
+		changed = true
+	}
+	return changed
+}
+
+// ServiceContainers returns the containers backing a compose service of
+// the compose project projectName: the one named containerName when set,
+// otherwise every container compose labelled with the service key (one per
+// replica). Containers of other projects that happen to use the same
+// service key or container name are left out.
+func (ds *DockerStatus) ServiceContainers(projectName, serviceKey, containerName string) []ContainerInfo {
 	ds.mu.RLock()
 	defer ds.mu.RUnlock()
-	return ds.RunningContainers[name]
+
+	if containerName != "" {
+		if info, ok := ds.Containers[containerName]; ok && info.Labels["com.docker.compose.project"] == projectName {
+			return []ContainerInfo{info}
+		}
+		return nil
+	}
+
+	var result []ContainerInfo
+	for _, info := range ds.Containers {
+		if info.Labels["com.docker.compose.project"] == projectName && info.Labels["com.docker.compose.service"] == serviceKey {
+			result = append(result, info)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
 }
 
 func (ds *DockerStatus) IsNetworkExists(name string) bool {
 	ds.mu.RLock()
 	defer ds.mu.RUnlock()
-	return ds.ExistingNetworks[name]
+	_, ok := ds.Networks[name]
+	return ok
 }
 
 func (ds *DockerStatus) IsVolumeExists(name string) bool {
 	ds.mu.RLock()
 	defer ds.mu.RUnlock()
-	return ds.ExistingVolumes[name]
+	_, ok := ds.Volumes[name]
+	return ok
 }
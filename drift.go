@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configHashLabel is set on every service written to a compose file. It
+// holds the hash of the service definition, so a running container can be
+// checked against the current configuration.
+const configHashLabel = "lazyrmss.config-hash"
+
+// serviceHash hashes the content of a service definition. Key order and
+// comments do not contribute, so only changes that matter to Compose make a
+// container stale.
+func serviceHash(svc *yaml.Node) string {
+	var v interface{}
+	if err := svc.Decode(&v); err != nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:12])
+}
+
+// configHashes returns the hash of every service in a composition, by key.
+func configHashes(compose *yaml.Node) map[string]string {
+	hashes := make(map[string]string)
+	services := mappingNode(compose, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return hashes
+	}
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		hashes[services.Content[i].Value] = serviceHash(services.Content[i+1])
+	}
+	return hashes
+}
+
+// withConfigHashes returns a copy of compose with the config hash label
+// added to every service. The hash is taken before the label is added.
+func withConfigHashes(compose *yaml.Node) *yaml.Node {
+	compose = cloneNode(compose)
+	services := mappingNode(compose, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return compose
+	}
+
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		svc := services.Content[i+1]
+		if svc.Kind != yaml.MappingNode {
+			continue
+		}
+		hash := serviceHash(svc)
+
+		labels := mappingNode(svc, "labels")
+		switch {
+		case labels != nil && labels.Kind == yaml.SequenceNode:
+			labels.Content = upsertSequence(labels.Content, scalarNode(configHashLabel+"="+hash), keyValueKey)
+		case labels != nil && labels.Kind == yaml.MappingNode:
+			if idx := mappingIndex(labels, configHashLabel); idx >= 0 {
+				labels.Content[idx+1] = scalarNode(hash)
+			} else {
+				labels.Content = append(labels.Content, scalarNode(configHashLabel), scalarNode(hash))
+			}
+		default:
+			labels = newMappingNode()
+			labels.Content = append(labels.Content, scalarNode(configHashLabel), scalarNode(hash))
+			if idx := mappingIndex(svc, "labels"); idx >= 0 {
+				svc.Content[idx+1] = labels
+			} else {
+				svc.Content = append(svc.Content, scalarNode("labels"), labels)
+			}
+		}
+	}
+	return compose
+}
+
+func cloneNode(n *yaml.Node) *yaml.Node {
+	if n == nil {
+		return nil
+	}
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = cloneNode(child)
+	}
+	return &c
+}
+
+// expectedHashes returns the config hash of every service of the current
+// compositions, by project name and service key, as projects may reuse a
+// service key. A project whose composition cannot be built is left out.
+// Options are resolved through r.
+func (a *App) expectedHashes(r *resolutions) map[string]map[string]string {
+	hashes := make(map[string]map[string]string)
+	for _, p := range a.projects() {
+		compose, err := a.buildComposeFrom(p.Categories, r)
+		if err != nil {
+			continue
+		}
+		hashes[p.Name] = configHashes(compose)
+	}
+	return hashes
+}
+
+// isStale reports whether a container was created from a different
+// definition of its service than the current one. Containers without the
+// label were not started by lazyrmss and are never considered stale.
+func isStale(c ContainerInfo, expected string) bool {
+	actual, ok := c.Labels[configHashLabel]
+	return ok && expected != "" && actual != expected
+}
+
+// staleServices returns the keys of enabled services of p with a container
+// that no longer matches the configuration, in composition order.
+func (a *App) staleServices(p project) []string {
+	if a.dockerStatus == nil {
+		return nil
+	}
+	compose, err := a.buildCompose(p.Categories)
+	if err != nil {
+		return nil
+	}
+	hashes := configHashes(compose)
+
+	var stale []string
+	for _, ref := range extractServiceRefs(compose) {
+		for _, c := range a.dockerStatus.ServiceContainers(p.Name, ref.Key, ref.ContainerName) {
+			if isStale(c, hashes[ref.Key]) {
+				stale = append(stale, ref.Key)
+				break
+			}
+		}
+	}
+	return stale
+}
+
+// confirmUpStale offers to re-create only the services of the active
+// project whose containers are stale.
+func (a *App) confirmUpStale() {
+	p := a.activeProject()
+	stale := a.staleServices(p)
+	if len(stale) == 0 {
+		a.notify("green", "No stale services")
+		return
+	}
+
+	msg := fmt.Sprintf("[yellow::b]Up Stale[-:-:-]\n\nRun [green]docker compose up -d[-] for %s?", strings.Join(stale, ", "))
+	a.confirmUp("Up Stale", msg, func() {
+		compose, err := a.buildCompose(p.Categories)
+		if err != nil {
+			return
+		}
+		a.runDockerCompose(p, compose, append([]string{"up", "-d"}, stale...)...)
+	})
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultDockerHost = "unix:///var/run/docker.sock"
+
+// engineClient is a minimal Docker Engine API client. It speaks plain HTTP,
+// either over a unix socket or a TCP address, so it needs no docker binary.
+type engineClient struct {
+	http *http.Client
+	base string
+}
+
+// newEngineClient creates a client for host, which takes the same form as
+// DOCKER_HOST (unix:///path or tcp://host:port). An empty host falls back
+// to DOCKER_HOST and then to the default daemon socket.
+func newEngineClient(host string) (*engineClient, error) {
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		host = defaultDockerHost
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("parsing docker host %q: %w", host, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		dialer := &net.Dialer{Timeout: 5 * time.Second}
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+		return &engineClient{
+			http: &http.Client{Transport: transport},
+			base: "http://docker",
+		}, nil
+	case "tcp", "http":
+		return &engineClient{
+			http: &http.Client{},
+			base: "http://" + u.Host,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported docker host %q", host)
+}
+
+// get issues a GET request against the API and decodes the JSON response.
+func (c *engineClient) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	endpoint := c.base + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		if apiErr.Message == "" {
+			apiErr.Message = resp.Status
+		}
+		return fmt.Errorf("docker api %s: %s", path, apiErr.Message)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// --- Resource types ---
+
+// ContainerInfo is the structured state of a single container.
+type ContainerInfo struct {
+	ID     string
+	Name   string
+	Image  string
+	State  string // created, running, paused, restarting, removing, exited or dead
+	Status string // human-readable, e.g. "Up 2 hours (healthy)"
+	Health string // healthy, unhealthy, starting, or empty without a healthcheck
+	// ExitCode is the last exit code of an exited container.
+	ExitCode int
+	Labels   map[string]string
+	Ports    []PortBinding
+}
+
+type PortBinding struct {
+	IP          string
+	PrivatePort int
+	PublicPort  int
+	Type        string
+}
+
+type NetworkInfo struct {
+	ID     string
+	Name   string
+	Driver string
+	Scope  string
+	Labels map[string]string
+}
+
+type VolumeInfo struct {
+	Name       string
+	Driver     string
+	Mountpoint string
+	Labels     map[string]string
+}
+
+// --- Queries ---
+
+type apiContainer struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	State  string            `json:"State"`
+	Status string            `json:"Status"`
+	Labels map[string]string `json:"Labels"`
+	Ports  []struct {
+		IP          string `json:"IP"`
+		PrivatePort int    `json:"PrivatePort"`
+		PublicPort  int    `json:"PublicPort"`
+		Type        string `json:"Type"`
+	} `json:"Ports"`
+}
+
+func (ac apiContainer) info() ContainerInfo {
+	info := ContainerInfo{
+		ID:       ac.ID,
+		Image:    ac.Image,
+		State:    ac.State,
+		Status:   ac.Status,
+		Health:   parseHealth(ac.Status),
+		ExitCode: parseExitCode(ac.Status),
+		Labels:   ac.Labels,
+	}
+	for _, p := range ac.Ports {
+		info.Ports = append(info.Ports, PortBinding{
+			IP:          p.IP,
+			PrivatePort: p.PrivatePort,
+			PublicPort:  p.PublicPort,
+			Type:        p.Type,
+		})
+	}
+	return info
+}
+
+// containers lists all containers, including stopped ones, keyed by name.
+func (c *engineClient) containers(ctx context.Context) (map[string]ContainerInfo, error) {
+	return c.listContainers(ctx, url.Values{"all": {"1"}})
+}
+
+// containerByID looks up a single container. A missing container is
+// reported as ok == false rather than as an error.
+func (c *engineClient) containerByID(ctx context.Context, id string) (ContainerInfo, bool, error) {
+	filters, _ := json.Marshal(map[string][]string{"id": {id}})
+	found, err := c.listContainers(ctx, url.Values{"all": {"1"}, "filters": {string(filters)}})
+	if err != nil {
+		return ContainerInfo{}, false, err
+	}
+	for _, info := range found {
+		return info, true, nil
+	}
+	return ContainerInfo{}, false, nil
+}
+
+func (c *engineClient) listContainers(ctx context.Context, query url.Values) (map[string]ContainerInfo, error) {
+	var list []apiContainer
+	if err := c.get(ctx, "/containers/json", query, &list); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]ContainerInfo, len(list))
+	for _, ac := range list {
+		info := ac.info()
+		for _, name := range ac.Names {
+			info.Name = strings.TrimPrefix(name, "/")
+			result[info.Name] = info
+		}
+	}
+	return result, nil
+}
+
+type apiNetwork struct {
+	ID     string            `json:"Id"`
+	Name   string            `json:"Name"`
+	Driver string            `json:"Driver"`
+	Scope  string            `json:"Scope"`
+	Labels map[string]string `json:"Labels"`
+}
+
+func (n apiNetwork) info() NetworkInfo {
+	return NetworkInfo{
+		ID:     n.ID,
+		Name:   n.Name,
+		Driver: n.Driver,
+		Scope:  n.Scope,
+		Labels: n.Labels,
+	}
+}
+
+func (c *engineClient) networks(ctx context.Context) (map[string]NetworkInfo, error) {
+	var list []apiNetwork
+	if err := c.get(ctx, "/networks", nil, &list); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]NetworkInfo, len(list))
+	for _, n := range list {
+		result[n.Name] = n.info()
+	}
+	return result, nil
+}
+
+func (c *engineClient) network(ctx context.Context, id string) (NetworkInfo, error) {
+	var n apiNetwork
+	if err := c.get(ctx, "/networks/"+url.PathEscape(id), nil, &n); err != nil {
+		return NetworkInfo{}, err
+	}
+	return n.info(), nil
+}
+
+type apiVolume struct {
+	Name       string            `json:"Name"`
+	Driver     string            `json:"Driver"`
+	Mountpoint string            `json:"Mountpoint"`
+	Labels     map[string]string `json:"Labels"`
+}
+
+func (v apiVolume) info() VolumeInfo {
+	return VolumeInfo{
+		Name:       v.Name,
+		Driver:     v.Driver,
+		Mountpoint: v.Mountpoint,
+		Labels:     v.Labels,
+	}
+}
+
+func (c *engineClient) volumes(ctx context.Context) (map[string]VolumeInfo, error) {
+	var resp struct {
+		Volumes []apiVolume `json:"Volumes"`
+	}
+	if err := c.get(ctx, "/volumes", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]VolumeInfo, len(resp.Volumes))
+	for _, v := range resp.Volumes {
+		result[v.Name] = v.info()
+	}
+	return result, nil
+}
+
+func (c *engineClient) volume(ctx context.Context, name string) (VolumeInfo, error) {
+	var v apiVolume
+	if err := c.get(ctx, "/volumes/"+url.PathEscape(name), nil, &v); err != nil {
+		return VolumeInfo{}, err
+	}
+	return v.info(), nil
+}
+
+// --- Logs ---
+
+// containerTTY reports whether the container was created with a TTY, in
+// which case its log stream is raw rather than multiplexed.
+func (c *engineClient) containerTTY(ctx context.Context, id string) (bool, error) {
+	var inspect struct {
+		Config struct {
+			Tty bool `json:"Tty"`
+		} `json:"Config"`
+	}
+	if err := c.get(ctx, "/containers/"+url.PathEscape(id)+"/json", nil, &inspect); err != nil {
+		return false, err
+	}
+	return inspect.Config.Tty, nil
+}
+
+// logs follows the stdout and stderr of a container, starting with the last
+// tail lines, and calls handle for every line. Each line is prefixed with
+// its RFC 3339 timestamp. It blocks until the stream ends or the context is
+// cancelled.
+func (c *engineClient) logs(ctx context.Context, id string, tail int, handle func(line string)) error {
+	tty, err := c.containerTTY(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{
+		"follow":     {"1"},
+		"stdout":     {"1"},
+		"stderr":     {"1"},
+		"timestamps": {"1"},
+		"tail":       {strconv.Itoa(tail)},
+	}
+	endpoint := c.base + "/containers/" + url.PathEscape(id) + "/logs?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("docker api logs: %s", resp.Status)
+	}
+
+	var body io.Reader = resp.Body
+	if !tty {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(demuxLogStream(pw, resp.Body))
+		}()
+		defer pr.Close()
+		body = pr
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		handle(strings.TrimRight(scanner.Text(), "\r"))
+	}
+	return scanner.Err()
+}
+
+// demuxLogStream copies the payload of a multiplexed stdout/stderr stream
+// to w. Each frame starts with an 8-byte header whose last four bytes hold
+// the big-endian payload size.
+func demuxLogStream(w io.Writer, r io.Reader) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(w, r, size); err != nil {
+			return err
+		}
+	}
+}
+
+// --- Events ---
+
+// engineEvent is a single message from the /events stream.
+type engineEvent struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+}
+
+// events subscribes to container, network and volume events that occurred
+// after since and calls handle for each one. It blocks until the stream
+// ends, the context is cancelled or a decoding error occurs.
+func (c *engineClient) events(ctx context.Context, since time.Time, handle func(engineEvent)) error {
+	filters, _ := json.Marshal(map[string][]string{"type": {"container", "network", "volume"}})
+	query := url.Values{
+		"since":   {fmt.Sprintf("%d", since.Unix())},
+		"filters": {string(filters)},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/events?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("docker api /events: %s", resp.Status)
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev engineEvent
+		if err := dec.Decode(&ev); err != nil {
+			return err
+		}
+		handle(ev)
+	}
+}
+
+// parseHealth extracts the healthcheck state from a container status line
+// such as "Up 5 minutes (healthy)" or "Up 3 seconds (health: starting)".
+func parseHealth(status string) string {
+	switch {
+	case strings.Contains(status, "(healthy)"):
+		return "healthy"
+	case strings.Contains(status, "(unhealthy)"):
+		return "unhealthy"
+	case strings.Contains(status, "(health: starting)"):
+		return "starting"
+	}
+	return ""
+}
+
+// parseExitCode extracts the exit code from a status line such as
+// "Exited (137) 2 minutes ago". It returns 0 when none is present.
+func parseExitCode(status string) int {
+	if !strings.HasPrefix(status, "Exited (") {
+		return 0
+	}
+	rest := strings.TrimPrefix(status, "Exited (")
+	end := strings.Index(rest, ")")
+	if end < 0 {
+		return 0
+	}
+	code, err := strconv.Atoi(rest[:end])
+	if err != nil {
+		return 0
+	}
+	return code
+}
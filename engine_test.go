@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestEngine serves handler on a unix socket in a temporary directory and
+// returns a client connected to it.
+func newTestEngine(t *testing.T, handler http.Handler) *engineClient {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	client, err := newEngineClient("unix://" + socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestEngineContainers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("all") != "1" {
+			t.Errorf("all = %q, want 1", r.URL.Query().Get("all"))
+		}
+		w.Write([]byte(`[{
+			"Id": "abc",
+			"Names": ["/web-1"],
+			"Image": "nginx:1",
+			"State": "running",
+			"Status": "Up 2 hours (healthy)",
+			"Labels": {"com.docker.compose.service": "web"},
+			"Ports": [{"IP": "0.0.0.0", "PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"}]
+		}, {
+			"Id": "def",
+			"Names": ["/job"],
+			"State": "exited",
+			"Status": "Exited (137) 2 minutes ago"
+		}]`))
+	})
+	client := newTestEngine(t, mux)
+
+	got, err := client.containers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]ContainerInfo{
+		"web-1": {
+			ID:     "abc",
+			Name:   "web-1",
+			Image:  "nginx:1",
+			State:  "running",
+			Status: "Up 2 hours (healthy)",
+			Health: "healthy",
+			Labels: map[string]string{"com.docker.compose.service": "web"},
+			Ports:  []PortBinding{{IP: "0.0.0.0", PrivatePort: 80, PublicPort: 8080, Type: "tcp"}},
+		},
+		"job": {
+			ID:       "def",
+			Name:     "job",
+			State:    "exited",
+			Status:   "Exited (137) 2 minutes ago",
+			ExitCode: 137,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("containers() = %+v, want %+v", got, want)
+	}
+}
+
+func TestEngineAPIError(t *testing.T) {
+	client := newTestEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"message": "daemon on fire"}`))
+	}))
+
+	_, err := client.containers(context.Background())
+	if err == nil || err.Error() != "docker api /containers/json: daemon on fire" {
+		t.Errorf("containers() error = %v", err)
+	}
+}
+
+func TestEngineNetworksAndVolumes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/networks", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Id": "n1", "Name": "backend", "Driver": "bridge", "Scope": "local", "Labels": {"a": "b"}}]`))
+	})
+	mux.HandleFunc("/volumes", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Volumes": [{"Name": "data", "Driver": "local", "Mountpoint": "/var/lib/docker/volumes/data/_data"}]}`))
+	})
+	client := newTestEngine(t, mux)
+
+	networks, err := client.networks(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNetworks := map[string]NetworkInfo{
+		"backend": {ID: "n1", Name: "backend", Driver: "bridge", Scope: "local", Labels: map[string]string{"a": "b"}},
+	}
+	if !reflect.DeepEqual(networks, wantNetworks) {
+		t.Errorf("networks() = %+v, want %+v", networks, wantNetworks)
+	}
+
+	volumes, err := client.volumes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantVolumes := map[string]VolumeInfo{
+		"data": {Name: "data", Driver: "local", Mountpoint: "/var/lib/docker/volumes/data/_data"},
+	}
+	if !reflect.DeepEqual(volumes, wantVolumes) {
+		t.Errorf("volumes() = %+v, want %+v", volumes, wantVolumes)
+	}
+}
+
+func TestEngineEvents(t *testing.T) {
+	since := time.Unix(1700000000, 0)
+	client := newTestEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.URL.Query().Get("since"); got != "1700000000" {
+			t.Errorf("since = %q", got)
+		}
+		var filters map[string][]string
+		json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+		if want := []string{"container", "network", "volume"}; !reflect.DeepEqual(filters["type"], want) {
+			t.Errorf("type filter = %v, want %v", filters["type"], want)
+		}
+		w.Write([]byte(`{"Type": "container", "Action": "health_status: healthy", "Actor": {"ID": "abc", "Attributes": {"name": "web-1"}}}` + "\n"))
+		w.Write([]byte(`{"Type": "volume", "Action": "destroy", "Actor": {"ID": "data"}}` + "\n"))
+	}))
+
+	var got []engineEvent
+	err := client.events(context.Background(), since, func(ev engineEvent) {
+		got = append(got, ev)
+	})
+	if err == nil {
+		t.Fatal("events() returned nil at the end of the stream")
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2", len(got))
+	}
+	if got[0].Type != "container" || got[0].Action != "health_status: healthy" || got[0].Actor.ID != "abc" || got[0].Actor.Attributes["name"] != "web-1" {
+		t.Errorf("first event = %+v", got[0])
+	}
+	if got[1].Type != "volume" || got[1].Action != "destroy" || got[1].Actor.ID != "data" {
+		t.Errorf("second event = %+v", got[1])
+	}
+}
+
+func logFrame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func TestDemuxLogStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		want    string
+		wantErr bool
+	}{
+		{"empty", nil, "", false},
+		{"single frame", logFrame(1, "hello\n"), "hello\n", false},
+		{
+			"stdout and stderr",
+			bytes.Join([][]byte{logFrame(1, "out\n"), logFrame(2, "err\n"), logFrame(1, "")}, nil),
+			"out\nerr\n",
+			false,
+		},
+		{"truncated header", []byte{1, 0, 0}, "", true},
+		{"truncated payload", logFrame(1, "hello")[:10], "he", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := demuxLogStream(&out, bytes.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHealth(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{"Up 5 minutes (healthy)", "healthy"},
+		{"Up 5 minutes (unhealthy)", "unhealthy"},
+		{"Up 3 seconds (health: starting)", "starting"},
+		{"Up 5 minutes", ""},
+		{"Exited (0) 1 second ago", ""},
+	}
+	for _, tt := range tests {
+		if got := parseHealth(tt.status); got != tt.want {
+			t.Errorf("parseHealth(%q) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestParseExitCode(t *testing.T) {
+	tests := []struct {
+		status string
+		want   int
+	}{
+		{"Exited (137) 2 minutes ago", 137},
+		{"Exited (0) 1 second ago", 0},
+		{"Exited (1)", 1},
+		{"Exited (", 0},
+		{"Exited (x) 1 second ago", 0},
+		{"Up 5 minutes", 0},
+	}
+	for _, tt := range tests {
+		if got := parseExitCode(tt.status); got != tt.want {
+			t.Errorf("parseExitCode(%q) = %d, want %d", tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envFileName is looked up at the resources root, in each category and in
+// each service directory. Deeper files override shallower ones.
+const envFileName = ".env"
+
+// envFiles returns the existing .env files that apply to opt, shallowest
+// first.
+func (a *App) envFiles(opt *Option) []string {
+	var files []string
+	for _, dir := range []string{a.config.ResourcesDir, filepath.Dir(opt.Dir), opt.Dir} {
+		path := filepath.Join(dir, envFileName)
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// envLookup returns the variable lookup for opt. As with Compose, the
+// process environment takes precedence over .env files. If one of the
+// files cannot be read, the failure is reported to the diagnostics log and
+// returned, and the lookup falls back to the process environment alone.
+func (a *App) envLookup(opt *Option) (func(string) (string, bool), error) {
+	vars := make(map[string]string)
+	var loadErr error
+	for _, path := range a.envFiles(opt) {
+		fileVars, err := loadEnvFile(path)
+		a.diagnostics.check("env", path, err)
+		if err != nil {
+			vars, loadErr = nil, err
+			break
+		}
+		for k, v := range fileVars {
+			vars[k] = v
+		}
+	}
+	return func(name string) (string, bool) {
+		if v, ok := os.LookupEnv(name); ok {
+			return v, true
+		}
+		v, ok := vars[name]
+		return v, ok
+	}, loadErr
+}
+
+// loadEnvFile parses a dotenv file: KEY=value lines, optionally prefixed
+// with "export", with single- or double-quoted values and # comments.
+func loadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=value", path, lineNo)
+		}
+		vars[key] = parseEnvValue(strings.TrimSpace(value))
+	}
+	return vars, scanner.Err()
+}
+
+// parseEnvValue unquotes a value and strips a trailing comment. Double
+// quotes support the usual backslash escapes.
+func parseEnvValue(value string) string {
+	if value != "" && (value[0] == '\'' || value[0] == '"') {
+		quote := value[0]
+		if end := closingQuote(value, quote); end > 0 {
+			inner := value[1:end]
+			if quote == '"' {
+				inner = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(inner)
+			}
+			return inner
+		}
+	}
+	if idx := strings.Index(value, " #"); idx >= 0 {
+		value = strings.TrimSpace(value[:idx])
+	}
+	return value
+}
+
+func closingQuote(value string, quote byte) int {
+	for i := 1; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && quote == '"':
+			i++
+		case value[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// --- Interpolation ---
+
+// interpolateNode substitutes variables in every scalar value of n, in
+// place, and returns the errors of values that could not be expanded,
+// located with origins if given. Mapping keys are left alone, as in
+// Compose.
+func interpolateNode(n *yaml.Node, lookup func(string) (string, bool), origins provenance) []error {
+	return interpolateScalars(n, lookup, origins, false)
+}
+
+// interpolateScalars implements interpolateNode. With escape set, every "$"
+// in an expanded value is written as "$$", so that the result reads the
+// same after docker compose interpolates it again. Values that cannot be
+// expanded are left as written either way.
+func interpolateScalars(n *yaml.Node, lookup func(string) (string, bool), origins provenance, escape bool) []error {
+	var errs []error
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		switch n.Kind {
+		case yaml.ScalarNode:
+			if !strings.Contains(n.Value, "$") {
+				return
+			}
+			value, err := interpolate(n.Value, lookup)
+			if err != nil {
+				if file, ok := origins[n]; ok {
+					errs = append(errs, fmt.Errorf("%s:%d: %w", filepath.Base(file), n.Line, err))
+				} else {
+					errs = append(errs, fmt.Errorf("line %d: %w", n.Line, err))
+				}
+				return
+			}
+			if escape {
+				value = strings.ReplaceAll(value, "$", "$$")
+			}
+			n.Value = value
+		case yaml.MappingNode:
+			for i := 1; i < len(n.Content); i += 2 {
+				walk(n.Content[i])
+			}
+		default:
+			for _, child := range n.Content {
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+	return errs
+}
+
+// interpolate expands $VAR, ${VAR} and the Compose forms ${VAR:-default},
+// ${VAR-default}, ${VAR:?error}, ${VAR?error}, ${VAR:+alt} and ${VAR+alt}.
+// "$$" yields a literal "$". Unset variables expand to the empty string.
+func interpolate(s string, lookup func(string) (string, bool)) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		switch next := s[i+1]; {
+		case next == '$':
+			b.WriteByte('$')
+			i += 2
+		case next == '{':
+			end := matchingBrace(s, i+1)
+			if end < 0 {
+				return "", fmt.Errorf("unclosed ${ in %q", s)
+			}
+			value, err := expandBraced(s[i+2:end], lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end + 1
+		case isVarStart(next):
+			j := i + 1
+			for j < len(s) && isVarChar(s[j]) {
+				j++
+			}
+			value, _ := lookup(s[i+1 : j])
+			b.WriteString(value)
+			i = j
+		default:
+			b.WriteByte('$')
+			i++
+		}
+	}
+	return b.String(), nil
+}
+
+// expandBraced expands the inside of ${...}.
+func expandBraced(expr string, lookup func(string) (string, bool)) (string, error) {
+	n := 0
+	for n < len(expr) && isVarChar(expr[n]) {
+		n++
+	}
+	name, rest := expr[:n], expr[n:]
+	if name == "" || !isVarStart(name[0]) {
+		return "", fmt.Errorf("invalid variable name in ${%s}", expr)
+	}
+
+	value, set := lookup(name)
+	if rest == "" {
+		return value, nil
+	}
+
+	colon := strings.HasPrefix(rest, ":")
+	rest = strings.TrimPrefix(rest, ":")
+	if rest == "" {
+		return "", fmt.Errorf("invalid expression ${%s}", expr)
+	}
+	present := set && (!colon || value != "")
+
+	op, arg := rest[0], rest[1:]
+	switch op {
+	case '-':
+		if present {
+			return value, nil
+		}
+		return interpolate(arg, lookup)
+	case '?':
+		if present {
+			return value, nil
+		}
+		msg, err := interpolate(arg, lookup)
+		if err != nil {
+			return "", err
+		}
+		if msg == "" {
+			return "", fmt.Errorf("required variable %s is missing a value", name)
+		}
+		return "", fmt.Errorf("required variable %s is missing a value: %s", name, msg)
+	case '+':
+		if present {
+			return interpolate(arg, lookup)
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("invalid expression ${%s}", expr)
+}
+
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isVarStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isVarChar(c byte) bool {
+	return isVarStart(c) || c >= '0' && c <= '9'
+}
+
+// interpolateOption expands the variables of resolved, the resolved YAML of
+// opt, with the environment that applies to opt.
+func (a *App) interpolateOption(opt *Option, resolved *yaml.Node, origins provenance) []error {
+	lookup, err := a.envLookup(opt)
+	errs := interpolateNode(resolved, lookup, origins)
+	if err != nil {
+		errs = append([]error{err}, errs...)
+	}
+	return errs
+}
+
+// interpolateForCompose expands the variables of resolved like
+// interpolateOption, for a compose file: literal "$" signs are escaped, so
+// docker compose runs every service with the values of its own .env files,
+// as shown in the preview. Values that cannot be expanded are left for
+// docker compose to report, and an unreadable .env file only costs the
+// service its own variables, see envLookup.
+func (a *App) interpolateForCompose(opt *Option, resolved *yaml.Node) {
+	lookup, _ := a.envLookup(opt)
+	interpolateScalars(resolved, lookup, nil, true)
+}
+
+// toggleInterpolation switches the preview between the YAML as written and
+// with variables expanded.
+func (a *App) toggleInterpolation() {
+	a.rawPreview = !a.rawPreview
+	a.updatePreview()
+}
+
+// composeArgs builds the docker arguments to run a compose command for p
+// against the compose file at path. The file is written with its variables
+// already expanded, see interpolateForCompose, so no .env file is passed.
+func composeArgs(p project, path string, args ...string) []string {
+	return append([]string{"compose", "-p", p.Name, "-f", path}, args...)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	vars := map[string]string{
+		"TAG":   "7-alpine",
+		"EMPTY": "",
+		"PRICE": "$5",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{in: "redis:latest", want: "redis:latest"},
+		{in: "redis:$TAG", want: "redis:7-alpine"},
+		{in: "redis:${TAG}", want: "redis:7-alpine"},
+		{in: "${TAG}${TAG}", want: "7-alpine7-alpine"},
+		{in: "$TAG-x", want: "7-alpine-x"},
+		{in: "$TAG_x", want: ""},
+		{in: "${UNSET}", want: ""},
+		{in: "$UNSET/x", want: "/x"},
+
+		// escapes and stray dollars
+		{in: "$$TAG", want: "$TAG"},
+		{in: "$${TAG}", want: "${TAG}"},
+		{in: "a $$ b", want: "a $ b"},
+		{in: "cost: 5$", want: "cost: 5$"},
+		{in: "$1 and $-", want: "$1 and $-"},
+		{in: "${PRICE}", want: "$5"},
+
+		// defaults
+		{in: "${UNSET:-7}", want: "7"},
+		{in: "${UNSET-7}", want: "7"},
+		{in: "${EMPTY:-7}", want: "7"},
+		{in: "${EMPTY-7}", want: ""},
+		{in: "${TAG:-7}", want: "7-alpine"},
+		{in: "${UNSET:-${TAG}}", want: "7-alpine"},
+		{in: "${UNSET:-}", want: ""},
+
+		// alternatives
+		{in: "${TAG:+on}", want: "on"},
+		{in: "${EMPTY:+on}", want: ""},
+		{in: "${EMPTY+on}", want: "on"},
+		{in: "${UNSET+on}", want: ""},
+		{in: "${TAG:+tag=${TAG}}", want: "tag=7-alpine"},
+
+		// required
+		{in: "${TAG:?needed}", want: "7-alpine"},
+		{in: "${EMPTY?needed}", want: ""},
+		{in: "${EMPTY:?needed}", wantErr: "required variable EMPTY is missing a value: needed"},
+		{in: "${UNSET?needed}", wantErr: "required variable UNSET is missing a value: needed"},
+		{in: "${UNSET:?}", wantErr: "required variable UNSET is missing a value"},
+
+		// malformed
+		{in: "${TAG", wantErr: `unclosed ${ in "${TAG"`},
+		{in: "${}", wantErr: "invalid variable name in ${}"},
+		{in: "${1A}", wantErr: "invalid variable name in ${1A}"},
+		{in: "${TAG:}", wantErr: "invalid expression ${TAG:}"},
+		{in: "${TAG!x}", wantErr: "invalid expression ${TAG!x}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := interpolate(tt.in, lookup)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterpolateScalarsEscape(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		if name == "PRICE" {
+			return "$5", true
+		}
+		return "", false
+	}
+	tests := []struct {
+		name   string
+		in     string
+		escape bool
+		want   string
+	}{
+		{"expanded", `{a: "${PRICE}", b: "$$HOME", c: plain}`, false, `{a: "$5", b: "$HOME", c: plain}`},
+		{"escaped", `{a: "${PRICE}", b: "$$HOME", c: plain}`, true, `{a: "$$5", b: "$$HOME", c: plain}`},
+		{"keys kept", `{"$PRICE": x}`, true, `{"$PRICE": x}`},
+		{"failed value kept", `{a: "${MISSING:?set it} $$"}`, true, `{a: "${MISSING:?set it} $$"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := parseTestNode(t, tt.in)
+			interpolateScalars(n, lookup, nil, tt.escape)
+			got := decodeTestNode(t, n)
+			want := decodeTestNode(t, parseTestNode(t, tt.want))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseEnvValue(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{``, ``},
+		{`plain`, `plain`},
+		{`with spaces inside`, `with spaces inside`},
+		{`value # comment`, `value`},
+		{`value#not-a-comment`, `value#not-a-comment`},
+		{`"double quoted"`, `double quoted`},
+		{`'single quoted'`, `single quoted`},
+		{`"7-alpine" # comment`, `7-alpine`},
+		{`"a # b"`, `a # b`},
+		{`"line\nbreak"`, "line\nbreak"},
+		{`"tab\there"`, "tab\there"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`'no \n escapes'`, `no \n escapes`},
+		{`"unclosed`, `"unclosed`},
+	}
+	for _, tt := range tests {
+		if got := parseEnvValue(tt.in); got != tt.want {
+			t.Errorf("parseEnvValue(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr string // after the file path
+	}{
+		{
+			name:    "empty",
+			content: "",
+			want:    map[string]string{},
+		},
+		{
+			name: "comments and blank lines",
+			content: `# header
+
+A=1
+  # indented comment
+B = 2 `,
+			want: map[string]string{"A": "1", "B": "2"},
+		},
+		{
+			name:    "export prefix",
+			content: "export TAG=7\nexport  NAME='x'\n",
+			want:    map[string]string{"TAG": "7", "NAME": "x"},
+		},
+		{
+			name:    "quoted values",
+			content: "A=\"7-alpine\" # c\nB='it''s'\nC=\"x=y\"\n",
+			want:    map[string]string{"A": "7-alpine", "B": "it", "C": "x=y"},
+		},
+		{
+			name:    "empty value",
+			content: "A=\nB=\"\"\n",
+			want:    map[string]string{"A": "", "B": ""},
+		},
+		{
+			name:    "later line wins",
+			content: "A=1\nA=2\n",
+			want:    map[string]string{"A": "2"},
+		},
+		{
+			name:    "missing equals",
+			content: "A=1\ngarbage\n",
+			wantErr: ":2: expected KEY=value",
+		},
+		{
+			name:    "missing key",
+			content: "=1\n",
+			wantErr: ":1: expected KEY=value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadEnvFile(path)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != path+tt.wantErr {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
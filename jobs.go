@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	// maxFinishedJobs bounds how many finished jobs are kept for the jobs
+	// panel.
+	maxFinishedJobs = 50
+	// jobKillDelay is how long an interrupted job gets to exit before it
+	// is killed.
+	jobKillDelay = 10 * time.Second
+)
+
+type jobStatus int
+
+const (
+	jobRunning jobStatus = iota
+	jobStopping
+	jobSucceeded
+	jobFailed
+	jobCancelled
+)
+
+func (s jobStatus) finished() bool {
+	return s >= jobSucceeded
+}
+
+func (s jobStatus) String() string {
+	switch s {
+	case jobRunning:
+		return "running"
+	case jobStopping:
+		return "stopping"
+	case jobSucceeded:
+		return "done"
+	case jobFailed:
+		return "failed"
+	default:
+		return "cancelled"
+	}
+}
+
+func (s jobStatus) color() string {
+	switch s {
+	case jobRunning:
+		return "yellow"
+	case jobStopping:
+		return "orange"
+	case jobSucceeded:
+		return "green"
+	case jobFailed:
+		return "red"
+	default:
+		return "gray"
+	}
+}
+
+// job is one docker command run in the background. Its output is captured
+// so it can be shown in the log panel at any time, while it runs or after.
+type job struct {
+	ID      int
+	Command string
+	Started time.Time
+
+	cmd    *exec.Cmd
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	status jobStatus
+	err    error
+	ended  time.Time
+	output bytes.Buffer
+}
+
+func (j *job) write(p []byte) {
+	j.mu.Lock()
+	j.output.Write(p)
+	j.mu.Unlock()
+}
+
+// outputFrom returns the output captured after the first offset bytes.
+func (j *job) outputFrom(offset int) []byte {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if offset >= j.output.Len() {
+		return nil
+	}
+	return append([]byte(nil), j.output.Bytes()[offset:]...)
+}
+
+func (j *job) finish(err error, cancelled bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.ended = time.Now()
+	j.err = err
+	switch {
+	case cancelled:
+		j.status = jobCancelled
+	case err != nil:
+		j.status = jobFailed
+	default:
+		j.status = jobSucceeded
+	}
+}
+
+func (j *job) state() (jobStatus, error, time.Duration) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	end := j.ended
+	if !j.status.finished() {
+		end = time.Now()
+	}
+	return j.status, j.err, end.Sub(j.Started)
+}
+
+// jobManager tracks running and recently finished jobs, oldest first.
+type jobManager struct {
+	mu     sync.Mutex
+	nextID int
+	jobs   []*job
+}
+
+func (m *jobManager) add(command string, cancel context.CancelFunc) *job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	j := &job{ID: m.nextID, Command: command, Started: time.Now(), cancel: cancel}
+	m.jobs = append(m.jobs, j)
+	m.prune()
+	return j
+}
+
+// prune drops the oldest finished jobs beyond maxFinishedJobs.
+func (m *jobManager) prune() {
+	finished := 0
+	for _, j := range m.jobs {
+		if status, _, _ := j.state(); status.finished() {
+			finished++
+		}
+	}
+	kept := m.jobs[:0]
+	for _, j := range m.jobs {
+		if status, _, _ := j.state(); status.finished() && finished > maxFinishedJobs {
+			finished--
+			continue
+		}
+		kept = append(kept, j)
+	}
+	m.jobs = kept
+}
+
+func (m *jobManager) list() []*job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*job(nil), m.jobs...)
+}
+
+// jobWriter captures the output of a job and passes it on to the log panel
+// while the job is shown there.
+type jobWriter struct {
+	a *App
+	j *job
+}
+
+func (w *jobWriter) Write(p []byte) (int, error) {
+	w.j.write(p)
+	w.a.app.QueueUpdateDraw(func() {
+		w.a.flushJobLog(w.j)
+	})
+	return len(p), nil
+}
+
+// --- Running jobs ---
+
+// startJob runs argv as a background job labelled command and shows it in
+// the log panel. done, if not nil, is called from the job goroutine once
+// the command has exited.
+func (a *App) startJob(command string, argv []string, done func(error)) *job {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := a.jobs.add(command, cancel)
+
+	// Cancelling interrupts the command like Ctrl-C, so docker compose can
+	// stop what it started. It is killed if it has not exited in time.
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = jobKillDelay
+	writer := &jobWriter{a: a, j: j}
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	j.cmd = cmd
+
+	a.showJob(j)
+	a.refreshJobsList()
+
+	err := cmd.Start()
+	go func() {
+		if err == nil {
+			err = cmd.Wait()
+		}
+		if done != nil {
+			done(err)
+		}
+		j.finish(err, ctx.Err() != nil)
+		cancel()
+		a.app.QueueUpdateDraw(func() {
+			a.jobFinished(j)
+		})
+	}()
+	return j
+}
+
+func (a *App) jobFinished(j *job) {
+	a.flushJobLog(j)
+	status, err, _ := j.state()
+	if a.shownJob == j {
+		a.writeJobResult(status, err)
+	} else {
+		a.notify(status.color(), fmt.Sprintf("Job #%d %s: %s", j.ID, status, j.Command))
+	}
+	a.refreshJobsList()
+}
+
+// cancelJob interrupts a running job. Cancelling a job that is already
+// stopping kills it.
+func (a *App) cancelJob(j *job) {
+	j.mu.Lock()
+	status := j.status
+	if status == jobRunning {
+		j.status = jobStopping
+	}
+	j.mu.Unlock()
+
+	switch status {
+	case jobRunning:
+		j.cancel()
+		j.write([]byte("\n^C interrupt sent, cancel again to kill\n"))
+	case jobStopping:
+		if j.cmd.Process != nil {
+			j.cmd.Process.Kill()
+		}
+		j.write([]byte("\n^C kill sent\n"))
+	default:
+		return
+	}
+	a.flushJobLog(j)
+	a.refreshJobsList()
+}
+
+// cancelCurrentJob cancels the job shown in the log panel or, if that one
+// has finished, the most recent running job.
+func (a *App) cancelCurrentJob() {
+	j := a.shownJob
+	if j != nil {
+		if status, _, _ := j.state(); status.finished() {
+			j = nil
+		}
+	}
+	if j == nil {
+		for _, candidate := range a.jobs.list() {
+			if status, _, _ := candidate.state(); !status.finished() {
+				j = candidate
+			}
+		}
+	}
+	if j == nil {
+		a.notify("gray", "No running job")
+		return
+	}
+	a.cancelJob(j)
+}
+
+// --- Log panel ---
+
+// showJob replaces the log panel with the output of j, which keeps
+// following the job while it runs.
+func (a *App) showJob(j *job) {
+	a.shownJob = j
+	a.jobLogOffset = 0
+	a.logView.Clear()
+	a.jobLog = tview.ANSIWriter(a.logView)
+	a.logView.SetTitle(fmt.Sprintf(" Log: job #%d ", j.ID))
+	fmt.Fprintf(a.logView, "[yellow]$ %s[-]\n", tview.Escape(j.Command))
+
+	a.flushJobLog(j)
+	if status, err, _ := j.state(); status.finished() {
+		a.writeJobResult(status, err)
+	}
+	a.logView.ScrollToEnd()
+}
+
+// detachJobLog stops showing a job in the log panel so that it can be used
+// for other output.
+func (a *App) detachJobLog() {
+	a.shownJob = nil
+	a.logView.SetTitle(" Log ")
+	a.logView.Clear()
+}
+
+// flushJobLog writes the output of j not yet shown to the log panel.
+func (a *App) flushJobLog(j *job) {
+	if a.shownJob != j {
+		return
+	}
+	data := j.outputFrom(a.jobLogOffset)
+	if len(data) == 0 {
+		return
+	}
+	a.jobLogOffset += len(data)
+	a.jobLog.Write(data)
+	a.logView.ScrollToEnd()
+}
+
+func (a *App) writeJobResult(status jobStatus, err error) {
+	switch status {
+	case jobSucceeded:
+		fmt.Fprintf(a.logView, "\n[green]✓ Done[-]\n")
+	case jobCancelled:
+		fmt.Fprintf(a.logView, "\n[gray]✗ Cancelled[-]\n")
+	default:
+		fmt.Fprintf(a.logView, "\n[red]✗ %v[-]\n", err)
+	}
+	a.logView.ScrollToEnd()
+}
+
+// --- Jobs panel ---
+
+func (a *App) showJobs() {
+	a.jobsOpen = true
+
+	a.jobsList = tview.NewList().
+		ShowSecondaryText(false).
+		SetHighlightFullLine(true).
+		SetSelectedStyle(tcell.StyleDefault.Background(tcell.NewRGBColor(68, 68, 88)))
+	a.jobsList.SetBorder(true).
+		SetTitle(" Jobs (enter: show log, x: interrupt / kill) ").
+		SetTitleAlign(tview.AlignCenter).
+		SetBorderColor(tcell.ColorGreen)
+	a.refreshJobsList()
+
+	// Start on the most recent job.
+	a.jobsList.SetCurrentItem(a.jobsList.GetItemCount() - 1)
+
+	a.pages.AddPage("jobs", modal(a.jobsList, 100, 20), true, true)
+	a.app.SetFocus(a.jobsList)
+}
+
+// refreshJobsList redraws the jobs panel, if open, keeping the selection.
+func (a *App) refreshJobsList() {
+	if !a.jobsOpen {
+		return
+	}
+	current := a.jobsList.GetCurrentItem()
+	a.jobsList.Clear()
+
+	a.listedJobs = a.jobs.list()
+	if len(a.listedJobs) == 0 {
+		a.jobsList.AddItem("[gray]No jobs yet[-]", "", 0, nil)
+		return
+	}
+	for _, j := range a.listedJobs {
+		j := j
+		status, _, elapsed := j.state()
+		label := fmt.Sprintf("#%-3d [%s]%-9s[-] %6s  %s", j.ID, status.color(), status, elapsed.Round(time.Second), tview.Escape(j.Command))
+		a.jobsList.AddItem(label, "", 0, func() {
+			a.closeJobs()
+			a.showJob(j)
+		})
+	}
+	a.jobsList.SetCurrentItem(current)
+}
+
+// selectedJob returns the job highlighted in the jobs panel.
+func (a *App) selectedJob() *job {
+	idx := a.jobsList.GetCurrentItem()
+	if idx < 0 || idx >= len(a.listedJobs) {
+		return nil
+	}
+	return a.listedJobs[idx]
+}
+
+func (a *App) closeJobs() {
+	a.jobsOpen = false
+	a.jobsList = nil
+	a.listedJobs = nil
+	a.pages.RemovePage("jobs")
+	a.app.SetFocus(a.panels[a.currentPanelIdx])
+	a.updateBorderColors()
+}
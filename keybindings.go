@@ -15,16 +15,80 @@ func (a *App) setupKeybindings() {
 			return event
 		}
 
+		if a.resourcesOpen {
+			if event.Key() == tcell.KeyEsc || event.Rune() == 'q' {
+				a.closeResources()
+				return nil
+			}
+			return event
+		}
+
+		if a.problemsOpen {
+			if event.Key() == tcell.KeyEsc || event.Rune() == 'q' {
+				a.closeProblems()
+				return nil
+			}
+			return event
+		}
+
+		if a.jobsOpen {
+			if event.Key() == tcell.KeyEsc || event.Rune() == 'q' {
+				a.closeJobs()
+				return nil
+			}
+			switch event.Rune() {
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			case 'x':
+				if j := a.selectedJob(); j != nil {
+					a.cancelJob(j)
+				}
+				return nil
+			}
+			return event
+		}
+
+		if a.searchOpen {
+			if event.Key() == tcell.KeyEsc {
+				a.closeLogSearch()
+				return nil
+			}
+			return event
+		}
+
+		if a.pickerOpen {
+			if event.Key() == tcell.KeyEsc || event.Rune() == 'q' {
+				a.closePicker()
+				return nil
+			}
+			switch event.Rune() {
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			}
+			return event
+		}
+
 		if a.confirmOpen {
 			if event.Key() == tcell.KeyEsc || event.Rune() == 'q' {
 				a.closeConfirm()
 				return nil
 			}
 			if event.Key() == tcell.KeyEnter {
-				if a.confirmAction != nil {
-					a.confirmAction()
+				action := a.confirmAction
+				a.closeConfirm()
+				if action != nil {
+					action()
 				}
+				return nil
+			}
+			if event.Rune() == 'n' && a.confirmAlt != nil {
+				alt := a.confirmAlt
 				a.closeConfirm()
+				alt()
 				return nil
 			}
 			return event
@@ -32,39 +96,93 @@ func (a *App) setupKeybindings() {
 
 		// === MAIN KEYBINDINGS ===
 
+		// Log stream controls while the stream pane is visible
+		if a.isStreamVisible() {
+			switch event.Key() {
+			case tcell.KeyPgDn:
+				a.scrollLogPane(1)
+				return nil
+			case tcell.KeyPgUp:
+				a.scrollLogPane(-1)
+				return nil
+			case tcell.KeyRune:
+				switch event.Rune() {
+				case 'f':
+					a.toggleLogFollow()
+					return nil
+				case 't':
+					a.toggleLogTimestamps()
+					return nil
+				case '/':
+					a.showLogSearch()
+					return nil
+				case 'n':
+					a.nextLogMatch(1)
+					return nil
+				case 'N':
+					a.nextLogMatch(-1)
+					return nil
+				case '+':
+					a.adjustLogTail(100)
+					return nil
+				case '-':
+					a.adjustLogTail(-100)
+					return nil
+				}
+			}
+		}
+
 		// Panel 0 only: Docker compose actions
 		if event.Key() == tcell.KeyRune && a.currentPanelIdx == 0 {
 			switch event.Rune() {
 			case 'U':
-				a.confirmGlobalAction("Up All", "up -d", tcell.ColorGreen, "up", "-d")
+				a.confirmUpAll()
+				return nil
+			case 'u':
+				a.confirmUpSingle()
+				return nil
+			case 'd':
+				a.confirmSingleAction("Down", tcell.ColorRed, "down")
 				return nil
 			case 'D':
 				a.confirmGlobalAction("Down All", "down", tcell.ColorRed, "down")
 				return nil
 			case 's':
-				a.confirmSingleAction("Stop", "stop", tcell.ColorYellow, func() { a.dockerDirectSingle("stop") })
+				a.confirmSingleAction("Stop", tcell.ColorYellow, "stop")
 				return nil
 			case 'S':
 				a.confirmGlobalAction("Stop All", "stop", tcell.ColorYellow, "stop")
 				return nil
 			case 'c':
-				a.confirmSingleAction("Start", "start", tcell.ColorGreen, func() { a.dockerDirectSingle("start") })
+				a.confirmSingleAction("Start", tcell.ColorGreen, "start")
 				return nil
 			case 'C':
 				a.confirmGlobalAction("Start All", "start", tcell.ColorGreen, "start")
 				return nil
 			case 'r':
-				a.confirmSingleAction("Restart", "restart", tcell.ColorYellow, func() { a.dockerDirectSingle("restart") })
+				a.confirmSingleAction("Restart", tcell.ColorYellow, "restart")
 				return nil
 			case 'R':
 				a.confirmGlobalAction("Restart All", "restart", tcell.ColorYellow, "restart")
 				return nil
 			case 'p':
-				a.confirmSingleAction("Pull", "pull", tcell.ColorBlue, func() { a.dockerPullSingle() })
+				a.confirmSingleAction("Pull", tcell.ColorBlue, "pull")
 				return nil
 			case 'P':
 				a.confirmGlobalAction("Pull All", "pull", tcell.ColorBlue, "pull")
 				return nil
+			case 'b':
+				a.confirmSingleAction("Build", tcell.ColorBlue, "build")
+				return nil
+			case 'B':
+				a.confirmGlobalAction("Build All", "build", tcell.ColorBlue, "build")
+				return nil
+			case 'x':
+				a.openShell()
+				return nil
+			case 'F':
+				a.confirmUpStale()
+				return nil
 			}
 		}
 
@@ -118,12 +236,36 @@ func (a *App) setupKeybindings() {
 			case 'e':
 				a.editResourceFile()
 				return nil
+			case 'a':
+				a.toggleAnnotate()
+				return nil
+			case '=':
+				a.cycleDiffMode()
+				return nil
+			case 'i':
+				a.toggleInterpolation()
+				return nil
 			case 'y':
 				a.copyPreviewToClipboard()
 				return nil
 			case 'Y':
 				a.copyGlobalComposeToClipboard()
 				return nil
+			case 'v':
+				a.showResources()
+				return nil
+			case '!':
+				a.showProblems()
+				return nil
+			case 'o':
+				a.showJobs()
+				return nil
+			case 'X':
+				a.cancelCurrentJob()
+				return nil
+			case 'L':
+				a.toggleLogStream()
+				return nil
 			case '?':
 				a.showHelp()
 				return nil
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	maxStreamLines    = 5000
+	streamFlushPeriod = 100 * time.Millisecond
+)
+
+// streamColors are assigned to containers in order when a service has
+// several, so their interleaved lines can be told apart.
+var streamColors = []string{"cyan", "magenta", "yellow", "blue", "green", "orange", "purple", "teal"}
+
+type logLine struct {
+	Container string
+	Timestamp time.Time
+	Text      string
+}
+
+// logStream follows the logs of every container of one option. Lines are
+// collected from one goroutine per container and flushed to the UI in
+// batches.
+type logStream struct {
+	opt    *Option
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	pending []logLine
+}
+
+func (s *logStream) push(line logLine) {
+	s.mu.Lock()
+	s.pending = append(s.pending, line)
+	s.mu.Unlock()
+}
+
+func (s *logStream) drain() []logLine {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines := s.pending
+	s.pending = nil
+	return lines
+}
+
+// logPane holds the streaming log view and its display settings, which
+// persist while the selection changes.
+type logPane struct {
+	view   *tview.TextView
+	stream *logStream
+
+	lines      []logLine
+	colors     map[string]string
+	follow     bool
+	timestamps bool
+	tail       int
+
+	search   string
+	matches  int
+	matchIdx int
+}
+
+func newLogPane(tail int) *logPane {
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetRegions(true).
+		SetWordWrap(true).
+		SetScrollable(true)
+	view.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderColor(tcell.ColorDefault)
+
+	return &logPane{
+		view:   view,
+		colors: make(map[string]string),
+		follow: true,
+		tail:   tail,
+	}
+}
+
+// --- Stream lifecycle ---
+
+func (a *App) toggleLogStream() {
+	if a.isStreamVisible() {
+		a.closeLogStream()
+		return
+	}
+	a.logPages.SwitchToPage("stream")
+	a.startLogStream(a.getSelectedOption())
+}
+
+func (a *App) isStreamVisible() bool {
+	name, _ := a.logPages.GetFrontPage()
+	return name == "stream"
+}
+
+func (a *App) closeLogStream() {
+	a.stopLogStream()
+	a.logPages.SwitchToPage("log")
+}
+
+func (a *App) stopLogStream() {
+	if a.logPane.stream != nil {
+		a.logPane.stream.cancel()
+		a.logPane.stream = nil
+	}
+}
+
+// syncLogStream restarts the stream when the selected option changed while
+// the log pane is visible.
+func (a *App) syncLogStream() {
+	if !a.isStreamVisible() {
+		return
+	}
+	opt := a.getSelectedOption()
+	if a.logPane.stream != nil && a.logPane.stream.opt == opt {
+		return
+	}
+	a.startLogStream(opt)
+}
+
+func (a *App) startLogStream(opt *Option) {
+	a.stopLogStream()
+
+	p := a.logPane
+	p.lines = nil
+	p.colors = make(map[string]string)
+	p.view.Clear()
+
+	if opt == nil {
+		a.updateStreamTitle(nil)
+		p.view.SetText("[white]No option selected[-]")
+		return
+	}
+
+	var containers []ContainerInfo
+	if a.dockerStatus != nil && a.dockerStatus.client != nil {
+		if resolved, err := resolveOption(opt, a.diagnostics); err == nil {
+			projectName := a.optionProject(opt).Name
+			for _, ref := range extractServiceRefs(resolved) {
+				containers = append(containers, a.dockerStatus.ServiceContainers(projectName, ref.Key, ref.ContainerName)...)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &logStream{opt: opt, cancel: cancel}
+	p.stream = stream
+	a.updateStreamTitle(opt)
+
+	if len(containers) == 0 {
+		p.view.SetText(fmt.Sprintf("[gray]No containers found for %s[-]", tview.Escape(opt.Name)))
+		return
+	}
+
+	for i, c := range containers {
+		if len(containers) > 1 {
+			p.colors[c.Name] = streamColors[i%len(streamColors)]
+		}
+		go a.followContainer(ctx, stream, c, p.tail)
+	}
+	go a.flushLogStream(ctx, stream)
+}
+
+func (a *App) followContainer(ctx context.Context, stream *logStream, c ContainerInfo, tail int) {
+	err := a.dockerStatus.client.logs(ctx, c.ID, tail, func(raw string) {
+		line := logLine{Container: c.Name, Text: raw}
+		if ts, text, ok := strings.Cut(raw, " "); ok {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				line.Timestamp = t
+				line.Text = text
+			}
+		}
+		stream.push(line)
+	})
+	if err != nil && ctx.Err() == nil {
+		stream.push(logLine{Container: c.Name, Text: fmt.Sprintf("[stream error: %v]", err)})
+	}
+}
+
+// flushLogStream periodically moves buffered lines into the pane until the
+// stream is cancelled.
+func (a *App) flushLogStream(ctx context.Context, stream *logStream) {
+	ticker := time.NewTicker(streamFlushPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			lines := stream.drain()
+			if len(lines) == 0 {
+				continue
+			}
+			a.app.QueueUpdateDraw(func() {
+				if a.logPane.stream != stream {
+					return
+				}
+				a.appendLogLines(lines)
+			})
+		}
+	}
+}
+
+// --- Rendering ---
+
+func (a *App) appendLogLines(lines []logLine) {
+	p := a.logPane
+	p.lines = append(p.lines, lines...)
+
+	if len(p.lines) > maxStreamLines {
+		p.lines = p.lines[len(p.lines)-maxStreamLines:]
+		if p.follow {
+			a.renderLogPane()
+		}
+		return
+	}
+
+	// While paused the buffer keeps growing but the view stays frozen.
+	if !p.follow {
+		return
+	}
+	for _, line := range lines {
+		p.view.Write([]byte(a.formatLogLine(line)))
+	}
+	p.view.ScrollToEnd()
+}
+
+func (a *App) renderLogPane() {
+	p := a.logPane
+	p.matches = 0
+
+	var b strings.Builder
+	for _, line := range p.lines {
+		b.WriteString(a.formatLogLine(line))
+	}
+	p.view.SetText(b.String())
+
+	if p.search != "" && p.matches > 0 {
+		if p.matchIdx >= p.matches {
+			p.matchIdx = p.matches - 1
+		}
+		p.view.Highlight(fmt.Sprintf("m%d", p.matchIdx))
+		p.view.ScrollToHighlight()
+		return
+	}
+	p.view.Highlight()
+	if p.follow {
+		p.view.ScrollToEnd()
+	}
+}
+
+// formatLogLine renders one line with its optional timestamp and container
+// prefix, wrapping search matches in numbered regions.
+func (a *App) formatLogLine(line logLine) string {
+	p := a.logPane
+	var b strings.Builder
+
+	if p.timestamps && !line.Timestamp.IsZero() {
+		b.WriteString(fmt.Sprintf("[gray]%s[-] ", line.Timestamp.Local().Format("2006-01-02 15:04:05")))
+	}
+	if color, ok := p.colors[line.Container]; ok {
+		b.WriteString(fmt.Sprintf("[%s]%s |[-] ", color, tview.Escape(line.Container)))
+	}
+
+	if p.search == "" {
+		b.WriteString(tview.Escape(line.Text))
+	} else {
+		b.WriteString(a.markMatches(line.Text))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func (a *App) markMatches(text string) string {
+	p := a.logPane
+	lower := strings.ToLower(text)
+	needle := strings.ToLower(p.search)
+	if len(lower) != len(text) {
+		// Case folding changed byte offsets; fall back to exact matching.
+		lower, needle = text, p.search
+	}
+
+	var b strings.Builder
+	for {
+		idx := strings.Index(lower, needle)
+		if idx < 0 {
+			b.WriteString(tview.Escape(text))
+			return b.String()
+		}
+		end := idx + len(needle)
+		b.WriteString(tview.Escape(text[:idx]))
+		b.WriteString(fmt.Sprintf(`["m%d"][black:yellow]%s[-:-][""]`, p.matches, tview.Escape(text[idx:end])))
+		p.matches++
+		text, lower = text[end:], lower[end:]
+	}
+}
+
+func (a *App) updateStreamTitle(opt *Option) {
+	p := a.logPane
+	if opt == nil {
+		p.view.SetTitle(" Logs ")
+		return
+	}
+
+	state := "following"
+	if !p.follow {
+		state = "paused"
+	}
+	title := fmt.Sprintf(" Logs: %s [%s] tail=%d ", opt.Name, state, p.tail)
+	if p.search != "" {
+		title += fmt.Sprintf("/%s (%d) ", p.search, p.matches)
+	}
+	p.view.SetTitle(tview.Escape(title))
+}
+
+// --- Controls ---
+
+func (a *App) toggleLogFollow() {
+	p := a.logPane
+	p.follow = !p.follow
+	if p.follow {
+		a.renderLogPane()
+	}
+	a.updateStreamTitle(a.streamOption())
+}
+
+func (a *App) toggleLogTimestamps() {
+	a.logPane.timestamps = !a.logPane.timestamps
+	a.renderLogPane()
+}
+
+// adjustLogTail changes the number of history lines fetched when a stream
+// starts and restarts the current stream to apply it.
+func (a *App) adjustLogTail(delta int) {
+	p := a.logPane
+	p.tail += delta
+	if p.tail < 0 {
+		p.tail = 0
+	}
+	a.startLogStream(a.streamOption())
+}
+
+func (a *App) nextLogMatch(delta int) {
+	p := a.logPane
+	if p.matches == 0 {
+		return
+	}
+	p.matchIdx = (p.matchIdx + delta + p.matches) % p.matches
+	p.view.Highlight(fmt.Sprintf("m%d", p.matchIdx))
+	p.view.ScrollToHighlight()
+}
+
+func (a *App) scrollLogPane(delta int) {
+	_, _, _, height := a.logPane.view.GetInnerRect()
+	row, col := a.logPane.view.GetScrollOffset()
+	row += delta * height
+	if row < 0 {
+		row = 0
+	}
+	a.logPane.view.ScrollTo(row, col)
+}
+
+func (a *App) streamOption() *Option {
+	if a.logPane.stream != nil {
+		return a.logPane.stream.opt
+	}
+	return a.getSelectedOption()
+}
+
+// --- Search prompt ---
+
+func (a *App) showLogSearch() {
+	a.searchOpen = true
+
+	input := tview.NewInputField().
+		SetLabel("/").
+		SetText(a.logPane.search).
+		SetFieldWidth(0)
+	input.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			a.logPane.search = input.GetText()
+			a.logPane.matchIdx = 0
+			a.renderLogPane()
+			a.updateStreamTitle(a.streamOption())
+		}
+		a.closeLogSearch()
+	})
+	input.SetBorder(true).
+		SetTitle(" Search logs ").
+		SetTitleAlign(tview.AlignCenter).
+		SetBorderColor(tcell.ColorGreen)
+
+	a.pages.AddPage("search", modal(input, 50, 3), true, true)
+	a.app.SetFocus(input)
+}
+
+func (a *App) closeLogSearch() {
+	a.searchOpen = false
+	a.pages.RemovePage("search")
+	a.app.SetFocus(a.panels[a.currentPanelIdx])
+	a.updateBorderColors()
+}
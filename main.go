@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -33,6 +34,7 @@ type Option struct {
 	Addons       []Addon
 	Enabled      bool
 	ActiveAddons map[string]bool
+	Manifest     *Manifest
 }
 
 type Addon struct {
@@ -40,6 +42,7 @@ type Addon struct {
 	File  string
 	Label string
 	Color string
+	Group string // variant group; exactly one member of a group is active
 }
 
 type addonDisplay struct {
@@ -70,11 +73,23 @@ type App struct {
 	addonsList  *tview.List
 	previewView *tview.TextView
 	logView     *tview.TextView
+	logPages    *tview.Pages
+	logPane     *logPane
 	statusBar   *tview.TextView
 
+	annotate   previewAnnotation
+	diffMode   int
+	rawPreview bool
+
 	helpOpen      bool
+	resourcesOpen bool
+	searchOpen    bool
+	pickerOpen    bool
 	confirmOpen   bool
 	confirmAction func()
+	confirmAlt    func()
+	problemsOpen  bool
+	jobsOpen      bool
 
 	config       *Config
 	categories   []Category
@@ -83,11 +98,33 @@ type App struct {
 
 	dockerStatus *DockerStatus
 	dockerCancel context.CancelFunc
+
+	jobs         *jobManager
+	jobsList     *tview.List
+	listedJobs   []*job
+	shownJob     *job
+	jobLog       io.Writer
+	jobLogOffset int
+
+	notifySeq int
+	notifying bool
+	conflicts []conflict
+
+	// diagnostics collects failures that were worked around, for the
+	// status bar and the problems list.
+	diagnostics *diagnosticLog
 }
 
 func main() {
 	a := &App{
-		options: make(map[string][]*Option),
+		options:     make(map[string][]*Option),
+		jobs:        &jobManager{},
+		diagnostics: &diagnosticLog{},
+	}
+
+	if len(os.Args) > 1 && isHelpArg(os.Args[1]) {
+		fmt.Print(cliUsage)
+		return
 	}
 
 	cfg, err := loadConfig()
@@ -102,24 +139,46 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := a.loadState(); err != nil {
+	stateNotes, err := a.loadState()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: could not load state: %v\n", err)
 	}
 
+	// Headless subcommands share discovery and state with the TUI
+	if len(os.Args) > 1 {
+		for _, note := range stateNotes {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", note)
+		}
+		for _, d := range a.diagnostics.list() {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", d)
+		}
+		a.diagnostics.setHandler(func(d diagnostic) {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", d)
+		})
+		os.Exit(a.runCLI(os.Args[1:]))
+	}
+
 	a.setupUI()
 	a.refreshAll()
+	if len(stateNotes) > 0 {
+		a.notify("yellow", strings.Join(stateNotes, "; "))
+	}
+	if n := len(a.diagnostics.list()); n > 0 {
+		a.notify("red", fmt.Sprintf("✗ %d error%s while loading (! for problems)", n, plural(n)))
+	}
+	a.watchDiagnostics()
 
-	// Initialize Docker status polling
-	a.dockerStatus = &DockerStatus{
-		RunningContainers: make(map[string]bool),
-		ExistingNetworks:  make(map[string]bool),
-		ExistingVolumes:   make(map[string]bool),
+	// Initialize Docker status watching over the Engine API
+	client, err := newEngineClient("")
+	if err != nil {
+		a.diagnostics.report("docker", "", err)
 	}
+	a.dockerStatus = newDockerStatus(client)
 	ctx, cancel := context.WithCancel(context.Background())
 	a.dockerCancel = cancel
 
 	interval := time.Duration(a.config.PollInterval) * time.Second
-	a.dockerStatus.StartPolling(ctx, interval, func() {
+	a.dockerStatus.StartWatching(ctx, interval, func() {
 		a.app.QueueUpdateDraw(func() {
 			a.refreshOptionsList()
 		})
@@ -195,6 +254,7 @@ func (a *App) cursorDown() {
 	if a.currentPanelIdx == 0 {
 		a.refreshAddonsList()
 		a.updatePanelTitles()
+		a.syncLogStream()
 	}
 	a.updatePreview()
 }
@@ -209,16 +269,25 @@ func (a *App) cursorUp() {
 	if a.currentPanelIdx == 0 {
 		a.refreshAddonsList()
 		a.updatePanelTitles()
+		a.syncLogStream()
 	}
 	a.updatePreview()
 }
 
 func (a *App) scrollPreviewDown() {
+	if a.annotate.enabled {
+		a.moveAnnotateCursor(1)
+		return
+	}
 	row, col := a.previewView.GetScrollOffset()
 	a.previewView.ScrollTo(row+1, col)
 }
 
 func (a *App) scrollPreviewUp() {
+	if a.annotate.enabled {
+		a.moveAnnotateCursor(-1)
+		return
+	}
 	row, col := a.previewView.GetScrollOffset()
 	if row > 0 {
 		a.previewView.ScrollTo(row-1, col)
@@ -233,6 +302,7 @@ func (a *App) nextTab() {
 	}
 	a.activeTabIdx = (a.activeTabIdx + 1) % len(a.categories)
 	a.refreshAll()
+	a.syncLogStream()
 }
 
 func (a *App) prevTab() {
@@ -241,4 +311,5 @@ func (a *App) prevTab() {
 	}
 	a.activeTabIdx = (a.activeTabIdx - 1 + len(a.categories)) % len(a.categories)
 	a.refreshAll()
+	a.syncLogStream()
 }
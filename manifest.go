@@ -0,0 +1,417 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifestName is the per-service file holding addon rules. It lives next
+// to base.yaml and is never treated as an addon itself.
+const manifestName = "meta"
+
+// Manifest describes how the addons of one service relate to each other
+// and which other services it needs.
+type Manifest struct {
+	Addons map[string]AddonRules `yaml:"addons"`
+	Groups map[string]AddonGroup `yaml:"groups"`
+	// DependsOn lists "<category>/<service>" entries that must be enabled
+	// for this service to work.
+	DependsOn []string `yaml:"depends_on"`
+}
+
+// AddonGroup is a set of mutually exclusive variants of which exactly one
+// is active. Groups can also be formed by naming addon files
+// "<group>.<variant>.yaml"; Default then picks the variant by full name.
+type AddonGroup struct {
+	Members []string `yaml:"members"`
+	Default string   `yaml:"default"`
+}
+
+// AddonRules constrains a single addon. Requires and Implies are both
+// enabled along with the addon; a required addon cannot be turned off while
+// its dependent is active, whereas an implied one can. Conflicts are
+// symmetric and refused.
+type AddonRules struct {
+	Requires  []string `yaml:"requires"`
+	Conflicts []string `yaml:"conflicts"`
+	Implies   []string `yaml:"implies"`
+}
+
+func loadManifest(dir string) (*Manifest, error) {
+	manifest := &Manifest{Addons: make(map[string]AddonRules)}
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestName+".yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil
+		}
+		return manifest, err
+	}
+	if err := yaml.Unmarshal(data, manifest); err != nil {
+		return &Manifest{Addons: make(map[string]AddonRules)}, fmt.Errorf("parsing %s.yaml: %w", manifestName, err)
+	}
+	if manifest.Addons == nil {
+		manifest.Addons = make(map[string]AddonRules)
+	}
+	return manifest, nil
+}
+
+// assignGroups records the group of every variant addon, from the manifest
+// or from the "<group>.<variant>" naming convention, and labels variants by
+// their variant name. A name prefix only forms a group when at least two
+// addons share it or the manifest declares the group, so a lone
+// "redis.conf.yaml" stays a plain addon that can be turned off.
+func (opt *Option) assignGroups() {
+	declared := make(map[string]string)
+	for group, g := range opt.Manifest.Groups {
+		for _, member := range g.Members {
+			declared[member] = group
+		}
+	}
+
+	prefixes := make(map[string]int)
+	for _, addon := range opt.Addons {
+		if _, ok := declared[addon.Name]; ok {
+			continue
+		}
+		if group, ok := variantGroup(addon.Name); ok {
+			prefixes[group]++
+		}
+	}
+
+	for i := range opt.Addons {
+		addon := &opt.Addons[i]
+		if group, ok := declared[addon.Name]; ok {
+			addon.Group = group
+			addon.Label = addon.Name
+			continue
+		}
+		group, ok := variantGroup(addon.Name)
+		if !ok {
+			continue
+		}
+		if _, isDeclared := opt.Manifest.Groups[group]; isDeclared || prefixes[group] >= 2 {
+			addon.Group = group
+			addon.Label = strings.TrimPrefix(addon.Name, group+".")
+		}
+	}
+}
+
+// variantGroup returns the group of an addon named "<group>.<variant>".
+func variantGroup(name string) (string, bool) {
+	group, variant, ok := strings.Cut(name, ".")
+	return group, ok && group != "" && variant != ""
+}
+
+// groupOf returns the group of the named addon, or "" for a plain addon.
+func (opt *Option) groupOf(name string) string {
+	for _, addon := range opt.Addons {
+		if addon.Name == name {
+			return addon.Group
+		}
+	}
+	return ""
+}
+
+// groupMembers returns the variants of group in display order.
+func (opt *Option) groupMembers(group string) []string {
+	var members []string
+	for _, addon := range opt.Addons {
+		if addon.Group == group {
+			members = append(members, addon.Name)
+		}
+	}
+	return members
+}
+
+// groups returns the names of all variant groups of opt, sorted.
+func (opt *Option) groups() []string {
+	seen := make(map[string]bool)
+	for _, addon := range opt.Addons {
+		if addon.Group != "" {
+			seen[addon.Group] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// defaultVariant returns the variant selected when none is active.
+func (opt *Option) defaultVariant(group string) string {
+	members := opt.groupMembers(group)
+	if len(members) == 0 {
+		return ""
+	}
+	if def := opt.Manifest.Groups[group].Default; def != "" {
+		for _, m := range members {
+			if m == def {
+				return def
+			}
+		}
+	}
+	return members[0]
+}
+
+// conflictsWith returns the addons that cannot be active together with
+// name, including those that declare the conflict from their side.
+func (m *Manifest) conflictsWith(name string) []string {
+	seen := make(map[string]bool)
+	for _, other := range m.Addons[name].Conflicts {
+		seen[other] = true
+	}
+	for other, rules := range m.Addons {
+		for _, c := range rules.Conflicts {
+			if c == name {
+				seen[other] = true
+			}
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// dependents returns the addons that require name.
+func (m *Manifest) dependents(name string) []string {
+	var result []string
+	for other, rules := range m.Addons {
+		for _, r := range rules.Requires {
+			if r == name {
+				result = append(result, other)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// --- Addon activation ---
+
+func (opt *Option) hasAddon(name string) bool {
+	for _, addon := range opt.Addons {
+		if addon.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// addonClosure returns name together with everything it transitively
+// requires or implies.
+func (opt *Option) addonClosure(name string) ([]string, error) {
+	var order []string
+	seen := make(map[string]bool)
+
+	var visit func(n, via string) error
+	visit = func(n, via string) error {
+		if seen[n] {
+			return nil
+		}
+		if !opt.hasAddon(n) {
+			if via == "" {
+				return fmt.Errorf("addon %q not found for %s", n, opt.Name)
+			}
+			return fmt.Errorf("%s needs addon %q, which %s does not provide", via, n, opt.Name)
+		}
+		seen[n] = true
+		order = append(order, n)
+		rules := opt.Manifest.Addons[n]
+		for _, dep := range rules.Requires {
+			if err := visit(dep, n); err != nil {
+				return err
+			}
+		}
+		for _, dep := range rules.Implies {
+			if err := visit(dep, n); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := visit(name, ""); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// enableAddon activates name and its dependencies. It refuses when any of
+// them conflicts with an active addon or with each other, leaving the
+// option unchanged. The returned list holds the addons enabled as
+// dependencies.
+func (opt *Option) enableAddon(name string) ([]string, error) {
+	closure, err := opt.addonClosure(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Selecting a variant replaces the active sibling in its group, so
+	// siblings are not counted as conflicts unless two are requested.
+	inClosure := make(map[string]bool)
+	chosen := make(map[string]string)
+	for _, n := range closure {
+		inClosure[n] = true
+		if group := opt.groupOf(n); group != "" {
+			if prev, ok := chosen[group]; ok {
+				return nil, fmt.Errorf("%s and %s are both variants of %s", prev, n, group)
+			}
+			chosen[group] = n
+		}
+	}
+	replaced := func(other string) bool {
+		group := opt.groupOf(other)
+		_, ok := chosen[group]
+		return group != "" && ok && !inClosure[other]
+	}
+	for _, n := range closure {
+		for _, other := range opt.Manifest.conflictsWith(n) {
+			if inClosure[other] || opt.ActiveAddons[other] && !replaced(other) {
+				return nil, fmt.Errorf("%s conflicts with %s", n, other)
+			}
+		}
+	}
+
+	for group, n := range chosen {
+		for _, sibling := range opt.groupMembers(group) {
+			if sibling != n {
+				delete(opt.ActiveAddons, sibling)
+			}
+		}
+	}
+
+	var added []string
+	for _, n := range closure {
+		if !opt.ActiveAddons[n] && n != name {
+			added = append(added, n)
+		}
+		opt.ActiveAddons[n] = true
+	}
+	return added, nil
+}
+
+// disableAddon deactivates name along with every active addon that
+// transitively requires it. The returned list holds those dependents. The
+// active variant of a group cannot be disabled, only replaced.
+func (opt *Option) disableAddon(name string) ([]string, error) {
+	var affected []string
+	seen := make(map[string]bool)
+	var visit func(n string)
+	visit = func(n string) {
+		if seen[n] || !opt.ActiveAddons[n] {
+			return
+		}
+		seen[n] = true
+		affected = append(affected, n)
+		for _, dep := range opt.Manifest.dependents(n) {
+			visit(dep)
+		}
+	}
+	visit(name)
+
+	for _, n := range affected {
+		if group := opt.groupOf(n); group != "" {
+			if n == name {
+				return nil, fmt.Errorf("%s is the selected %s variant; select another one instead", n, group)
+			}
+			return nil, fmt.Errorf("%s is required by %s, the selected %s variant", name, n, group)
+		}
+	}
+
+	var removed []string
+	for _, n := range affected {
+		delete(opt.ActiveAddons, n)
+		if n != name {
+			removed = append(removed, n)
+		}
+	}
+	return removed, nil
+}
+
+// normalizeAddons repairs an activation set restored from state so it
+// satisfies the manifest: unknown addons are dropped, missing requirements
+// are enabled, and of two conflicting addons the one first in name order
+// is deactivated. It returns a description of every change.
+func (opt *Option) normalizeAddons() []string {
+	var notes []string
+	ref := opt.Category + "/" + opt.Name
+
+	for name := range opt.ActiveAddons {
+		if !opt.hasAddon(name) {
+			delete(opt.ActiveAddons, name)
+			notes = append(notes, fmt.Sprintf("%s: dropped unknown addon %s", ref, name))
+		}
+	}
+
+	active := sortedKeys(opt.ActiveAddons)
+	for _, addon := range active {
+		if !opt.ActiveAddons[addon] {
+			continue
+		}
+		delete(opt.ActiveAddons, addon)
+		added, err := opt.enableAddon(addon)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("%s: disabled %s: %v", ref, addon, err))
+			continue
+		}
+		if len(added) > 0 {
+			notes = append(notes, fmt.Sprintf("%s: enabled %s, needed by %s", ref, strings.Join(added, ", "), addon))
+		}
+	}
+
+	// Every group needs exactly one variant. Selecting the default is
+	// routine, so it is not reported.
+	for _, group := range opt.groups() {
+		selected := false
+		for _, member := range opt.groupMembers(group) {
+			selected = selected || opt.ActiveAddons[member]
+		}
+		if selected {
+			continue
+		}
+		def := opt.defaultVariant(group)
+		if _, err := opt.enableAddon(def); err != nil {
+			notes = append(notes, fmt.Sprintf("%s: no %s variant selected: %v", ref, group, err))
+		}
+	}
+
+	sort.Strings(notes)
+	return notes
+}
+
+// addonNote explains why an addon is constrained, for display next to it.
+// It is empty when nothing is worth mentioning.
+func (opt *Option) addonNote(name string) string {
+	if !opt.ActiveAddons[name] {
+		for _, other := range opt.Manifest.conflictsWith(name) {
+			if opt.ActiveAddons[other] {
+				return "conflicts with " + other
+			}
+		}
+	} else {
+		var by []string
+		for _, dep := range opt.Manifest.dependents(name) {
+			if opt.ActiveAddons[dep] {
+				by = append(by, dep)
+			}
+		}
+		if len(by) > 0 {
+			return "required by " + strings.Join(by, ", ")
+		}
+	}
+
+	if requires := opt.Manifest.Addons[name].Requires; len(requires) > 0 {
+		return "requires " + strings.Join(requires, ", ")
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
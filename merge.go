@@ -0,0 +1,333 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Merge directives, as understood by Docker Compose when merging files.
+const (
+	// tagReset removes the key from the merged result, whatever its value:
+	// "ports: !reset []" or "command: !reset null".
+	tagReset = "!reset"
+	// tagOverride replaces the merged value entirely instead of merging
+	// maps or appending lists.
+	tagOverride = "!override"
+)
+
+// loadYAMLNode parses a YAML file and returns its top-level node, keeping
+// tags that a plain map decode would lose. An empty file yields an empty
+// mapping.
+func loadYAMLNode(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return newMappingNode(), nil
+	}
+	return doc.Content[0], nil
+}
+
+func newMappingNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+// mergeNodes merges src into dst and returns the result. Maps merge
+// recursively, lists append and scalars from src override dst, unless src
+// carries a merge directive. Lists of well-known service fields are merged
+// by key instead, see mergeServiceList. dst may be modified in place.
+func mergeNodes(dst, src *yaml.Node) *yaml.Node {
+	return mergeNodesAt(dst, src, nil)
+}
+
+func mergeNodesAt(dst, src *yaml.Node, path []string) *yaml.Node {
+	if src.Tag == tagOverride || dst == nil {
+		return applyDirectives(src)
+	}
+
+	if field, ok := serviceField(path); ok {
+		if merged, ok := mergeServiceList(field, dst, src); ok {
+			return merged
+		}
+	}
+
+	if dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key, val := src.Content[i], src.Content[i+1]
+			idx := mappingIndex(dst, key.Value)
+
+			if val.Tag == tagReset {
+				if idx >= 0 {
+					dst.Content = append(dst.Content[:idx], dst.Content[idx+2:]...)
+				}
+				continue
+			}
+			if idx < 0 {
+				dst.Content = append(dst.Content, key, applyDirectives(val))
+				continue
+			}
+			dst.Content[idx+1] = mergeNodesAt(dst.Content[idx+1], val, append(path, key.Value))
+		}
+		return dst
+	}
+
+	if dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode {
+		for _, item := range src.Content {
+			dst.Content = append(dst.Content, applyDirectives(item))
+		}
+		return dst
+	}
+
+	return applyDirectives(src)
+}
+
+// applyDirectives resolves merge directives in a subtree that is taken over
+// as a whole: reset keys are dropped and override tags cleared, so the
+// result encodes as plain YAML.
+func applyDirectives(n *yaml.Node) *yaml.Node {
+	if n.Tag == tagOverride || n.Tag == tagReset {
+		n.Tag = ""
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		content := n.Content[:0]
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			if val.Tag == tagReset {
+				continue
+			}
+			content = append(content, key, applyDirectives(val))
+		}
+		n.Content = content
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			n.Content[i] = applyDirectives(item)
+		}
+	}
+	return n
+}
+
+// mappingIndex returns the index of key in a mapping node's content, or -1.
+func mappingIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// --- Key-aware list merging ---
+
+// serviceField returns the field name when path points at a field of a
+// service definition, i.e. services.<name>.<field>.
+func serviceField(path []string) (string, bool) {
+	if len(path) == 3 && path[0] == "services" {
+		return path[2], true
+	}
+	return "", false
+}
+
+// serviceListKeys maps service fields whose list entries are identified by
+// a key. An entry from an addon replaces the entry with the same key in
+// place; entries with new keys are appended.
+var serviceListKeys = map[string]func(*yaml.Node) string{
+	"ports":   portKey,
+	"volumes": mountTargetKey,
+	"devices": mountTargetKey,
+}
+
+// keyValueFields can be written either as "KEY=value" lists or as maps and
+// are merged by variable name.
+var keyValueFields = map[string]bool{
+	"environment": true,
+	"labels":      true,
+}
+
+// mergeServiceList merges the lists of well-known service fields by key,
+// following the Compose merge rules. It reports false for any other field,
+// or when the shapes do not allow a keyed merge.
+func mergeServiceList(field string, dst, src *yaml.Node) (*yaml.Node, bool) {
+	if keyValueFields[field] {
+		return mergeKeyValues(dst, src)
+	}
+	keyFn, ok := serviceListKeys[field]
+	if !ok || dst.Kind != yaml.SequenceNode || src.Kind != yaml.SequenceNode {
+		return nil, false
+	}
+	for _, item := range src.Content {
+		dst.Content = upsertSequence(dst.Content, applyDirectives(item), keyFn)
+	}
+	return dst, true
+}
+
+// upsertSequence replaces the first item sharing item's key, or appends.
+// Items without a key are always appended.
+func upsertSequence(items []*yaml.Node, item *yaml.Node, keyFn func(*yaml.Node) string) []*yaml.Node {
+	key := keyFn(item)
+	if key != "" {
+		for i, existing := range items {
+			if keyFn(existing) == key {
+				items[i] = item
+				return items
+			}
+		}
+	}
+	return append(items, item)
+}
+
+// mergeKeyValues merges environment-style fields. The result keeps the form
+// used by dst; src entries are converted when the forms differ.
+func mergeKeyValues(dst, src *yaml.Node) (*yaml.Node, bool) {
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		// Plain map merge already replaces values by key.
+		return nil, false
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.SequenceNode:
+		return mergeNodes(dst, keyValueListToMap(src)), true
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.MappingNode:
+		// Variables reset in map form are removed from the list.
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			if src.Content[i+1].Tag == tagReset {
+				dst.Content = removeKeyValue(dst.Content, src.Content[i].Value)
+			}
+		}
+		src = keyValueMapToList(applyDirectives(src))
+	case dst.Kind != yaml.SequenceNode || src.Kind != yaml.SequenceNode:
+		return nil, false
+	}
+
+	for _, item := range src.Content {
+		dst.Content = upsertSequence(dst.Content, applyDirectives(item), keyValueKey)
+	}
+	return dst, true
+}
+
+// keyValueKey returns the variable name of a "KEY=value" or "KEY" entry.
+func keyValueKey(n *yaml.Node) string {
+	if n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	key, _, _ := strings.Cut(n.Value, "=")
+	return key
+}
+
+// removeKeyValue drops the "KEY=value" or "KEY" entries of key.
+func removeKeyValue(items []*yaml.Node, key string) []*yaml.Node {
+	kept := items[:0]
+	for _, item := range items {
+		if keyValueKey(item) != key {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// keyValueListToMap converts "KEY=value" entries to map form. The entry
+// nodes are reused as keys so their provenance is kept.
+func keyValueListToMap(list *yaml.Node) *yaml.Node {
+	m := newMappingNode()
+	for _, item := range list.Content {
+		if item.Kind != yaml.ScalarNode {
+			continue
+		}
+		key, value, hasValue := strings.Cut(item.Value, "=")
+		// An explicit null, as an empty value is quoted in flow style.
+		valNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null", Line: item.Line, Column: item.Column}
+		if hasValue {
+			valNode.Tag = "!!str"
+			valNode.Value = value
+		}
+		item.Tag, item.Value, item.Style = "!!str", key, 0
+		m.Content = append(m.Content, item, valNode)
+	}
+	return m
+}
+
+// keyValueMapToList converts a map to "KEY=value" entries, reusing the key
+// nodes as entries.
+func keyValueMapToList(m *yaml.Node) *yaml.Node {
+	list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		key, val := m.Content[i], m.Content[i+1]
+		entry := key.Value
+		if val.Kind == yaml.ScalarNode && val.ShortTag() != "!!null" {
+			entry += "=" + val.Value
+		}
+		key.Tag, key.Value, key.Style = "!!str", entry, 0
+		list.Content = append(list.Content, key)
+	}
+	return list
+}
+
+// portKey identifies a port mapping by its container port and protocol,
+// for both the short ("127.0.0.1:8080:80/udp") and long syntax.
+func portKey(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		spec, proto, ok := strings.Cut(n.Value, "/")
+		if !ok {
+			proto = "tcp"
+		}
+		parts := strings.Split(spec, ":")
+		return parts[len(parts)-1] + "/" + proto
+	case yaml.MappingNode:
+		target := mappingValue(n, "target")
+		if target == "" {
+			return ""
+		}
+		proto := mappingValue(n, "protocol")
+		if proto == "" {
+			proto = "tcp"
+		}
+		return target + "/" + proto
+	}
+	return ""
+}
+
+// mountTargetKey identifies a volume or device by its path inside the
+// container, for both the short ("src:target:mode") and long syntax.
+func mountTargetKey(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		parts := strings.Split(n.Value, ":")
+		if len(parts) == 1 {
+			return parts[0]
+		}
+		return parts[1]
+	case yaml.MappingNode:
+		return mappingValue(n, "target")
+	}
+	return ""
+}
+
+// mappingNode returns the node stored under key in a mapping, or nil.
+func mappingNode(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	if idx := mappingIndex(m, key); idx >= 0 {
+		return m.Content[idx+1]
+	}
+	return nil
+}
+
+// mappingValue returns the scalar value stored under key, or "".
+func mappingValue(m *yaml.Node, key string) string {
+	if n := mappingNode(m, key); n != nil && n.Kind == yaml.ScalarNode {
+		return n.Value
+	}
+	return ""
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func parseTestNode(t *testing.T, src string) *yaml.Node {
+	t.Helper()
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		t.Fatalf("parsing %q: %v", src, err)
+	}
+	return doc.Content[0]
+}
+
+// decodeTestNode turns n into plain Go values, so results can be compared
+// regardless of YAML style.
+func decodeTestNode(t *testing.T, n *yaml.Node) interface{} {
+	t.Helper()
+	data, err := yaml.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		t.Fatalf("decoding merged YAML %q: %v", data, err)
+	}
+	return v
+}
+
+func TestMergeNodes(t *testing.T) {
+	tests := []struct {
+		name string
+		base string
+		over string
+		want string
+	}{
+		// environment and labels
+		{
+			name: "environment list into list",
+			base: `services: {app: {environment: [A=1, B=2]}}`,
+			over: `services: {app: {environment: [B=3, C=4]}}`,
+			want: `services: {app: {environment: [A=1, B=3, C=4]}}`,
+		},
+		{
+			name: "environment list into map",
+			base: `services: {app: {environment: {A: "1", B: "2"}}}`,
+			over: `services: {app: {environment: [B=3, C=4]}}`,
+			want: `services: {app: {environment: {A: "1", B: "3", C: "4"}}}`,
+		},
+		{
+			name: "environment map into list",
+			base: `services: {app: {environment: [A=1, B=2]}}`,
+			over: `services: {app: {environment: {B: "3", C: "4"}}}`,
+			want: `services: {app: {environment: [A=1, B=3, C=4]}}`,
+		},
+		{
+			name: "environment map into map",
+			base: `services: {app: {environment: {A: "1", B: "2"}}}`,
+			over: `services: {app: {environment: {B: "3"}}}`,
+			want: `services: {app: {environment: {A: "1", B: "3"}}}`,
+		},
+		{
+			name: "labels list into list",
+			base: `services: {app: {labels: [com.example.a=1, com.example.b=2]}}`,
+			over: `services: {app: {labels: [com.example.b=3]}}`,
+			want: `services: {app: {labels: [com.example.a=1, com.example.b=3]}}`,
+		},
+		{
+			name: "labels list into map",
+			base: `services: {app: {labels: {com.example.a: "1"}}}`,
+			over: `services: {app: {labels: [com.example.a=2, com.example.b=3]}}`,
+			want: `services: {app: {labels: {com.example.a: "2", com.example.b: "3"}}}`,
+		},
+		{
+			name: "labels map into list",
+			base: `services: {app: {labels: [com.example.a=1]}}`,
+			over: `services: {app: {labels: {com.example.a: "2", com.example.b: "3"}}}`,
+			want: `services: {app: {labels: [com.example.a=2, com.example.b=3]}}`,
+		},
+
+		// KEY entries without a value, passed through from the host
+		{
+			name: "bare key replaces value in list",
+			base: `services: {app: {environment: [A=1, B=2]}}`,
+			over: `services: {app: {environment: [A]}}`,
+			want: `services: {app: {environment: [A, B=2]}}`,
+		},
+		{
+			name: "value replaces bare key in list",
+			base: `services: {app: {environment: [A, B=2]}}`,
+			over: `services: {app: {environment: [A=1]}}`,
+			want: `services: {app: {environment: [A=1, B=2]}}`,
+		},
+		{
+			name: "bare key into map",
+			base: `services: {app: {environment: {A: "1"}}}`,
+			over: `services: {app: {environment: [A, B]}}`,
+			want: `services: {app: {environment: {A: null, B: null}}}`,
+		},
+		{
+			name: "null value into list",
+			base: `services: {app: {environment: [A=1]}}`,
+			over: `services: {app: {environment: {A: null, B: ""}}}`,
+			want: `services: {app: {environment: [A, B=]}}`,
+		},
+
+		// ports
+		{
+			name: "port replaced by container port",
+			base: `services: {app: {ports: ["8080:80", "443:443"]}}`,
+			over: `services: {app: {ports: ["9090:80"]}}`,
+			want: `services: {app: {ports: ["9090:80", "443:443"]}}`,
+		},
+		{
+			name: "port with host IP",
+			base: `services: {app: {ports: ["8080:80"]}}`,
+			over: `services: {app: {ports: ["127.0.0.1:8080:80"]}}`,
+			want: `services: {app: {ports: ["127.0.0.1:8080:80"]}}`,
+		},
+		{
+			name: "udp port is a different key",
+			base: `services: {app: {ports: ["53:53"]}}`,
+			over: `services: {app: {ports: ["53:53/udp"]}}`,
+			want: `services: {app: {ports: ["53:53", "53:53/udp"]}}`,
+		},
+		{
+			name: "udp port replaced",
+			base: `services: {app: {ports: ["53:53/udp"]}}`,
+			over: `services: {app: {ports: ["127.0.0.1:5353:53/udp"]}}`,
+			want: `services: {app: {ports: ["127.0.0.1:5353:53/udp"]}}`,
+		},
+		{
+			name: "long port syntax replaces short",
+			base: `services: {app: {ports: ["8080:80"]}}`,
+			over: `services: {app: {ports: [{target: 80, published: "9090", host_ip: 127.0.0.1}]}}`,
+			want: `services: {app: {ports: [{target: 80, published: "9090", host_ip: 127.0.0.1}]}}`,
+		},
+		{
+			name: "long port syntax keeps protocol apart",
+			base: `services: {app: {ports: [{target: 53, published: "53"}]}}`,
+			over: `services: {app: {ports: [{target: 53, published: "53", protocol: udp}]}}`,
+			want: `services: {app: {ports: [{target: 53, published: "53"}, {target: 53, published: "53", protocol: udp}]}}`,
+		},
+
+		// volumes and devices
+		{
+			name: "volume replaced by target",
+			base: `services: {app: {volumes: ["data:/var/lib/data", "./conf:/etc/app:ro"]}}`,
+			over: `services: {app: {volumes: ["other:/var/lib/data"]}}`,
+			want: `services: {app: {volumes: ["other:/var/lib/data", "./conf:/etc/app:ro"]}}`,
+		},
+		{
+			name: "long volume syntax replaces short",
+			base: `services: {app: {volumes: ["data:/var/lib/data"]}}`,
+			over: `services: {app: {volumes: [{type: tmpfs, target: /var/lib/data}]}}`,
+			want: `services: {app: {volumes: [{type: tmpfs, target: /var/lib/data}]}}`,
+		},
+		{
+			name: "anonymous volume appended",
+			base: `services: {app: {volumes: ["data:/var/lib/data"]}}`,
+			over: `services: {app: {volumes: ["/cache"]}}`,
+			want: `services: {app: {volumes: ["data:/var/lib/data", "/cache"]}}`,
+		},
+		{
+			name: "device replaced by target",
+			base: `services: {app: {devices: ["/dev/dri/card0:/dev/dri/card0", "/dev/kfd:/dev/kfd"]}}`,
+			over: `services: {app: {devices: ["/dev/dri/card1:/dev/dri/card0:rwm"]}}`,
+			want: `services: {app: {devices: ["/dev/dri/card1:/dev/dri/card0:rwm", "/dev/kfd:/dev/kfd"]}}`,
+		},
+
+		// other lists
+		{
+			name: "unknown service list appends",
+			base: `services: {app: {dns: [1.1.1.1], cap_add: [NET_ADMIN]}}`,
+			over: `services: {app: {dns: [1.1.1.1, 8.8.8.8], cap_add: [SYS_TIME]}}`,
+			want: `services: {app: {dns: [1.1.1.1, 1.1.1.1, 8.8.8.8], cap_add: [NET_ADMIN, SYS_TIME]}}`,
+		},
+		{
+			name: "keyed field name outside a service appends",
+			base: `x-defaults: {ports: ["8080:80"]}`,
+			over: `x-defaults: {ports: ["9090:80"]}`,
+			want: `x-defaults: {ports: ["8080:80", "9090:80"]}`,
+		},
+
+		// merge directives
+		{
+			name: "reset removes a keyed list",
+			base: `services: {app: {ports: ["8080:80"], environment: [A=1]}}`,
+			over: `services: {app: {ports: !reset [], environment: !reset {}}}`,
+			want: `services: {app: {}}`,
+		},
+		{
+			name: "reset variable in map form",
+			base: `services: {app: {environment: {A: "1", B: "2"}}}`,
+			over: `services: {app: {environment: {A: !reset null}}}`,
+			want: `services: {app: {environment: {B: "2"}}}`,
+		},
+		{
+			name: "reset variable of a list",
+			base: `services: {app: {environment: [A=1, B=2]}}`,
+			over: `services: {app: {environment: {A: !reset null, C: "3"}}}`,
+			want: `services: {app: {environment: [B=2, C=3]}}`,
+		},
+		{
+			name: "reset then add",
+			base: `services: {app: {volumes: ["data:/data"]}}`,
+			over: `services: {app: {volumes: !reset [], image: redis}}`,
+			want: `services: {app: {image: redis}}`,
+		},
+		{
+			name: "override replaces a keyed list",
+			base: `services: {app: {ports: ["8080:80", "443:443"]}}`,
+			over: `services: {app: {ports: !override ["9090:80"]}}`,
+			want: `services: {app: {ports: ["9090:80"]}}`,
+		},
+		{
+			name: "override replaces environment across forms",
+			base: `services: {app: {environment: [A=1, B=2]}}`,
+			over: `services: {app: {environment: !override {C: "3"}}}`,
+			want: `services: {app: {environment: {C: "3"}}}`,
+		},
+		{
+			name: "override replaces an unknown list",
+			base: `services: {app: {command: [sh, -c, "sleep 1"]}}`,
+			over: `services: {app: {command: !override [redis-server]}}`,
+			want: `services: {app: {command: [redis-server]}}`,
+		},
+		{
+			name: "reset inside an added service is dropped",
+			base: `services: {app: {image: nginx}}`,
+			over: `services: {db: {image: postgres, ports: !reset []}}`,
+			want: `services: {app: {image: nginx}, db: {image: postgres}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decodeTestNode(t, mergeNodes(parseTestNode(t, tt.base), parseTestNode(t, tt.over)))
+			want := decodeTestNode(t, parseTestNode(t, tt.want))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("merged\n%v\nwant\n%v", got, want)
+			}
+		})
+	}
+}
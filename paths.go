@@ -0,0 +1,132 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The merged compose file is written to a temporary directory, so relative
+// paths in service files would resolve against it instead of the service
+// directory. resolvePaths rewrites them to absolute paths while the file
+// they were written in is still known.
+
+// resolvePaths makes the relative paths of a loaded service file absolute,
+// relative to dir: bind mount sources, build contexts, env_file entries and
+// the files of top-level configs and secrets. Missing build contexts are
+// filled in after merging, see defaultBuildContexts.
+func resolvePaths(doc *yaml.Node, dir string) {
+	if services := mappingNode(doc, "services"); services != nil && services.Kind == yaml.MappingNode {
+		for i := 1; i < len(services.Content); i += 2 {
+			resolveServicePaths(services.Content[i], dir)
+		}
+	}
+	for _, section := range []string{"configs", "secrets"} {
+		defs := mappingNode(doc, section)
+		if defs == nil || defs.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 1; i < len(defs.Content); i += 2 {
+			resolvePath(mappingNode(defs.Content[i], "file"), dir)
+		}
+	}
+}
+
+func resolveServicePaths(svc *yaml.Node, dir string) {
+	if svc.Kind != yaml.MappingNode {
+		return
+	}
+
+	if volumes := mappingNode(svc, "volumes"); volumes != nil && volumes.Kind == yaml.SequenceNode {
+		for _, item := range volumes.Content {
+			switch item.Kind {
+			case yaml.ScalarNode:
+				source, rest, ok := strings.Cut(item.Value, ":")
+				if ok && isBindSource(source) {
+					item.Value = joinPath(dir, source) + ":" + rest
+				}
+			case yaml.MappingNode:
+				// In the long syntax the type tells bind mounts apart,
+				// so "source: html" is a path as well.
+				if mappingValue(item, "type") == "bind" {
+					resolvePath(mappingNode(item, "source"), dir)
+				}
+			}
+		}
+	}
+
+	if build := mappingNode(svc, "build"); build != nil {
+		if build.Kind == yaml.ScalarNode {
+			resolvePath(build, dir)
+		} else {
+			resolvePath(mappingNode(build, "context"), dir)
+		}
+	}
+
+	if envFile := mappingNode(svc, "env_file"); envFile != nil {
+		switch envFile.Kind {
+		case yaml.ScalarNode:
+			resolvePath(envFile, dir)
+		case yaml.SequenceNode:
+			for _, item := range envFile.Content {
+				if item.Kind == yaml.MappingNode {
+					resolvePath(mappingNode(item, "path"), dir)
+				} else {
+					resolvePath(item, dir)
+				}
+			}
+		}
+	}
+}
+
+// defaultBuildContexts sets the context of every build section of doc that
+// has none to dir, since Compose would otherwise build in the directory of
+// the temporary compose file. It runs on the merged YAML, so an addon that
+// only changes the dockerfile keeps the context of the base file.
+func defaultBuildContexts(doc *yaml.Node, dir string) {
+	services := mappingNode(doc, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 1; i < len(services.Content); i += 2 {
+		build := mappingNode(services.Content[i], "build")
+		if build == nil || build.Kind != yaml.MappingNode || mappingIndex(build, "context") >= 0 {
+			continue
+		}
+		build.Content = append(build.Content, scalarNode("context"), scalarNode(strings.ReplaceAll(dir, "$", "$$")))
+	}
+}
+
+// resolvePath rewrites the scalar n in place if it holds a relative path.
+func resolvePath(n *yaml.Node, dir string) {
+	if n == nil || n.Kind != yaml.ScalarNode || !isRelativePath(n.Value) {
+		return
+	}
+	n.Value = joinPath(dir, n.Value)
+}
+
+// isRelativePath reports whether p is a relative filesystem path. Paths
+// starting with ~ or a variable, and remote build contexts, are left to
+// Compose.
+func isRelativePath(p string) bool {
+	switch {
+	case p == "", filepath.IsAbs(p), strings.HasPrefix(p, "~"), strings.HasPrefix(p, "$"):
+		return false
+	case strings.Contains(p, "://"), strings.HasPrefix(p, "git@"):
+		return false
+	}
+	return true
+}
+
+// isBindSource reports whether a volume source is a relative host path, as
+// opposed to a named volume.
+func isBindSource(source string) bool {
+	return source == "." || source == ".." || strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+// joinPath joins a relative path to dir. A "$" in dir is escaped so that
+// Compose does not take it for a variable.
+func joinPath(dir, p string) string {
+	return filepath.Join(strings.ReplaceAll(dir, "$", "$$"), p)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+)
+
+// defaultProjectName is the compose project name unless project_name is
+// set in the config.
+const defaultProjectName = "lazyrmss"
+
+// project is a docker compose project: the categories whose enabled
+// services are brought up and torn down together.
+type project struct {
+	Name       string
+	Categories []Category
+}
+
+// projects returns one project for all categories or, with
+// project_per_category, one per category named <project_name>-<category>.
+func (a *App) projects() []project {
+	name := composeProjectName(a.config.ProjectName)
+	if !a.config.ProjectPerCategory {
+		return []project{{Name: name, Categories: a.categories}}
+	}
+	projects := make([]project, len(a.categories))
+	for i, cat := range a.categories {
+		projects[i] = project{Name: composeProjectName(name + "-" + cat.Name), Categories: []Category{cat}}
+	}
+	return projects
+}
+
+// activeProject returns the project the active tab belongs to.
+func (a *App) activeProject() project {
+	projects := a.projects()
+	if a.config.ProjectPerCategory && a.activeTabIdx < len(projects) {
+		return projects[a.activeTabIdx]
+	}
+	if len(projects) == 0 {
+		return project{Name: composeProjectName(a.config.ProjectName)}
+	}
+	return projects[0]
+}
+
+// optionProject returns the project opt belongs to.
+func (a *App) optionProject(opt *Option) project {
+	for _, p := range a.projects() {
+		for _, cat := range p.Categories {
+			if cat.Name == opt.Category {
+				return p
+			}
+		}
+	}
+	return a.activeProject()
+}
+
+// composeProjectName turns name into a valid compose project name: lower
+// case letters, digits, dashes and underscores, starting with a letter or
+// digit.
+func composeProjectName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	if name = strings.TrimLeft(b.String(), "-_"); name == "" {
+		return defaultProjectName
+	}
+	return name
+}
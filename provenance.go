@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rivo/tview"
+	"gopkg.in/yaml.v3"
+)
+
+// provenance maps the nodes of a resolved option to the file they were
+// loaded from. Merging moves nodes between trees without copying them, so
+// the mapping survives the merge. A nil provenance records nothing.
+type provenance map[*yaml.Node]string
+
+func (p provenance) record(n *yaml.Node, file string) {
+	if p == nil {
+		return
+	}
+	p[n] = file
+	for _, child := range n.Content {
+		p.record(child, file)
+	}
+}
+
+// lineSource is the file and line that contributed a rendered line.
+type lineSource struct {
+	File string
+	Line int
+}
+
+// sourceLines returns the source of every line of rendered, the YAML text of
+// resolved. The text is parsed again and walked alongside resolved to tie
+// its line numbers to the merged nodes. A key line is attributed to its
+// scalar value, so an addon overriding a value owns the line; lines of
+// nested maps and lists belong to the file that introduced the key.
+func sourceLines(resolved *yaml.Node, origins provenance, rendered string) []lineSource {
+	lines := make([]lineSource, strings.Count(rendered, "\n"))
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(rendered), &doc); err != nil || len(doc.Content) == 0 {
+		return lines
+	}
+
+	set := func(line int, n *yaml.Node) {
+		if line < 1 || line > len(lines) || lines[line-1].File != "" {
+			return
+		}
+		if file, ok := origins[n]; ok {
+			lines[line-1] = lineSource{File: file, Line: n.Line}
+		}
+	}
+
+	var walk func(m, r *yaml.Node)
+	walk = func(m, r *yaml.Node) {
+		if m.Kind != r.Kind || len(m.Content) != len(r.Content) {
+			return
+		}
+		switch m.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(m.Content); i += 2 {
+				key, val := m.Content[i], m.Content[i+1]
+				if _, ok := origins[val]; ok && val.Kind == yaml.ScalarNode {
+					set(r.Content[i].Line, val)
+				}
+				set(r.Content[i].Line, key)
+				walk(val, r.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for i, item := range m.Content {
+				set(r.Content[i].Line, item)
+				walk(item, r.Content[i])
+			}
+		}
+	}
+	walk(resolved, doc.Content[0])
+
+	// Continuation lines of multi-line values belong to the line above.
+	text := strings.Split(rendered, "\n")
+	for i := 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(text[i])
+		if lines[i].File == "" && trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			lines[i] = lines[i-1]
+		}
+	}
+	return lines
+}
+
+// --- Annotated preview ---
+
+// previewAnnotation is the state of the preview mode that labels every line
+// with the file it came from.
+type previewAnnotation struct {
+	enabled bool
+	opt     *Option
+	lines   []lineSource
+	cursor  int
+}
+
+type sourceLabel struct {
+	name  string
+	color string
+}
+
+// sourceLabels names the files of opt for the preview gutter.
+func sourceLabels(opt *Option) map[string]sourceLabel {
+	labels := map[string]sourceLabel{
+		opt.BaseFile: {name: "base", color: "gray"},
+	}
+	for _, addon := range opt.Addons {
+		labels[addon.File] = sourceLabel{name: addon.Name, color: addon.Color}
+	}
+	return labels
+}
+
+func (a *App) toggleAnnotate() {
+	a.annotate.enabled = !a.annotate.enabled
+	a.annotate.cursor = 0
+	if a.annotate.enabled {
+		a.diffMode = diffOff
+	} else {
+		a.previewView.Highlight()
+	}
+	a.updatePreview()
+}
+
+// showAnnotatedPreview renders yamlStr with a gutter naming the source of
+// each line and a cursor line selectable with J/K.
+func (a *App) showAnnotatedPreview(opt *Option, resolved *yaml.Node, origins provenance, yamlStr string) {
+	if a.annotate.opt != opt {
+		a.annotate.opt = opt
+		a.annotate.cursor = 0
+	}
+	a.annotate.lines = sourceLines(resolved, origins, yamlStr)
+
+	labels := sourceLabels(opt)
+	width := 0
+	for _, label := range labels {
+		width = max(width, len(label.name))
+	}
+
+	highlighted := strings.Split(highlightCode(yamlStr, "yaml"), "\n")
+	var b strings.Builder
+	for i, src := range a.annotate.lines {
+		label := labels[src.File]
+		if label.color == "" {
+			label.color = "gray"
+		}
+		fmt.Fprintf(&b, "[\"l%d\"][%s]%-*s[-] │ %s[\"\"]\n", i, label.color, width, tview.Escape(label.name), highlighted[i])
+	}
+	a.previewView.SetText(b.String())
+
+	if a.annotate.cursor >= len(a.annotate.lines) {
+		a.annotate.cursor = len(a.annotate.lines) - 1
+	}
+	a.moveAnnotateCursor(0)
+}
+
+func (a *App) moveAnnotateCursor(delta int) {
+	n := len(a.annotate.lines)
+	if n == 0 {
+		return
+	}
+	a.annotate.cursor = min(max(a.annotate.cursor+delta, 0), n-1)
+	a.previewView.Highlight(fmt.Sprintf("l%d", a.annotate.cursor))
+	a.previewView.ScrollToHighlight()
+
+	title := fmt.Sprintf(" %s [sources] ", a.annotate.opt.Name)
+	if src, ok := a.annotatedSource(); ok {
+		title = fmt.Sprintf(" %s ← %s:%d ", a.annotate.opt.Name, filepath.Base(src.File), src.Line)
+	}
+	a.previewView.SetTitle(tview.Escape(title))
+}
+
+// annotatedSource returns the source of the line under the preview cursor.
+func (a *App) annotatedSource() (lineSource, bool) {
+	if !a.annotate.enabled || a.annotate.cursor < 0 || a.annotate.cursor >= len(a.annotate.lines) {
+		return lineSource{}, false
+	}
+	src := a.annotate.lines[a.annotate.cursor]
+	return src, src.File != ""
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// defaultShells is the fallback chain tried after any configured shell.
+var defaultShells = []string{"bash", "sh"}
+
+// shellProbeTimeout bounds each check for whether a shell exists.
+const shellProbeTimeout = 5 * time.Second
+
+// shellChain returns the shells to try for opt, most preferred first. A
+// shell can be configured per "category/service" or per service name.
+func (a *App) shellChain(opt *Option) []string {
+	var chain []string
+	if sh, ok := a.config.Shells[opt.Category+"/"+opt.Name]; ok && sh != "" {
+		chain = append(chain, sh)
+	} else if sh, ok := a.config.Shells[opt.Name]; ok && sh != "" {
+		chain = append(chain, sh)
+	}
+	for _, sh := range defaultShells {
+		if len(chain) == 0 || chain[0] != sh {
+			chain = append(chain, sh)
+		}
+	}
+	return chain
+}
+
+// openShell starts an interactive shell in a container of the selected
+// option, asking which one when the service has several.
+func (a *App) openShell() {
+	opt := a.getSelectedOption()
+	if opt == nil {
+		return
+	}
+	resolved, err := resolveOption(opt, a.diagnostics)
+	if err != nil {
+		return
+	}
+
+	var containers []string
+	if a.dockerStatus != nil {
+		projectName := a.optionProject(opt).Name
+		for _, ref := range extractServiceRefs(resolved) {
+			for _, c := range a.dockerStatus.ServiceContainers(projectName, ref.Key, ref.ContainerName) {
+				if c.State == "running" {
+					containers = append(containers, c.Name)
+				}
+			}
+		}
+	}
+
+	switch len(containers) {
+	case 0:
+		a.notify("yellow", fmt.Sprintf("No running container for %s", opt.Name))
+	case 1:
+		a.execShell(containers[0], a.shellChain(opt))
+	default:
+		a.showPicker(fmt.Sprintf("Shell into %s", opt.Name), containers, func(container string) {
+			a.execShell(container, a.shellChain(opt))
+		})
+	}
+}
+
+// execShell suspends the UI and runs the first shell from chain that
+// exists in the container. Shells are probed non-interactively first so a
+// failing command inside a working shell never triggers the fallback. The
+// probes run in the background, each bounded by shellProbeTimeout, so a
+// hung daemon never freezes the UI. Progress goes to the status bar, so a
+// job shown in the log panel keeps streaming there.
+func (a *App) execShell(container string, chain []string) {
+	a.notify("gray", fmt.Sprintf("Looking for a shell in %s...", container))
+
+	go func() {
+		shell := probeShell(container, chain)
+		a.app.QueueUpdateDraw(func() {
+			if shell == "" {
+				a.notify("red", fmt.Sprintf("✗ no usable shell in %s (tried %v)", container, chain))
+				return
+			}
+			a.runShell(container, shell)
+		})
+	}()
+}
+
+// probeShell returns the first shell from chain that runs in container, or
+// "" if none does.
+func probeShell(container string, chain []string) string {
+	for _, sh := range chain {
+		ctx, cancel := context.WithTimeout(context.Background(), shellProbeTimeout)
+		err := exec.CommandContext(ctx, "docker", "exec", container, sh, "-c", "exit 0").Run()
+		cancel()
+		if err == nil {
+			return sh
+		}
+	}
+	return ""
+}
+
+func (a *App) runShell(container, shell string) {
+	var err error
+	a.app.Suspend(func() {
+		cmd := exec.Command("docker", "exec", "-it", container, shell)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+	})
+	if err != nil {
+		a.notify("red", fmt.Sprintf("✗ %s in %s: %v", shell, container, err))
+	} else {
+		a.notify("green", fmt.Sprintf("✓ Left %s in %s", shell, container))
+	}
+}
+
+// --- Picker modal ---
+
+func (a *App) showPicker(title string, items []string, onSelect func(string)) {
+	a.pickerOpen = true
+
+	list := tview.NewList().
+		ShowSecondaryText(false).
+		SetHighlightFullLine(true).
+		SetSelectedStyle(tcell.StyleDefault.Background(tcell.NewRGBColor(68, 68, 88)))
+	for _, item := range items {
+		list.AddItem(tview.Escape(item), "", 0, nil)
+	}
+	list.SetSelectedFunc(func(idx int, _, _ string, _ rune) {
+		a.closePicker()
+		onSelect(items[idx])
+	})
+
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf(" %s ", title)).
+		SetTitleAlign(tview.AlignCenter).
+		SetBorderColor(tcell.ColorGreen)
+
+	a.pages.AddPage("picker", modal(list, 50, len(items)+2), true, true)
+	a.app.SetFocus(list)
+}
+
+func (a *App) closePicker() {
+	a.pickerOpen = false
+	a.pages.RemovePage("picker")
+	a.app.SetFocus(a.panels[a.currentPanelIdx])
+	a.updateBorderColors()
+}
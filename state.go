@@ -13,20 +13,25 @@ type OptionState struct {
 	Addons  []string `yaml:"addons"`
 }
 
-func (a *App) loadState() error {
+// loadState restores enabled services and addons. Addon selections that
+// violate a service's manifest are repaired, and each repair is returned as
+// a note.
+func (a *App) loadState() ([]string, error) {
 	data, err := os.ReadFile(a.stateFilePath())
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return nil, nil
 		}
-		return err
+		return nil, err
 	}
 
 	var state map[string]map[string]OptionState
 	if err := yaml.Unmarshal(data, &state); err != nil {
-		return err
+		return nil, err
 	}
 
+	var notes []string
+
 	for catName, catState := range state {
 		options, ok := a.options[catName]
 		if !ok {
@@ -35,14 +40,16 @@ func (a *App) loadState() error {
 		for _, opt := range options {
 			if optState, ok := catState[opt.Name]; ok {
 				opt.Enabled = optState.Enabled
+				opt.ActiveAddons = make(map[string]bool)
 				for _, addonName := range optState.Addons {
 					opt.ActiveAddons[addonName] = true
 				}
+				notes = append(notes, opt.normalizeAddons()...)
 			}
 		}
 	}
 
-	return nil
+	return notes, nil
 }
 
 func (a *App) saveState() error {
@@ -74,3 +81,67 @@ func (a *App) saveState() error {
 	os.MkdirAll(filepath.Dir(a.stateFilePath()), 0755)
 	return os.WriteFile(a.stateFilePath(), data, 0644)
 }
+
+// saveAppliedCompose records the composition that was just brought up, so
+// the current one can be diffed against what is running.
+func saveAppliedCompose(projectName, yamlStr string) error {
+	os.MkdirAll(filepath.Dir(appliedComposePath(projectName)), 0755)
+	return os.WriteFile(appliedComposePath(projectName), []byte(yamlStr), 0644)
+}
+
+// recordApplied saves compose as the applied composition of projectName
+// after an up. When the up only targeted services, those services replace
+// or add to their definitions in the recorded composition, and the rest of
+// it is kept as it was.
+func recordApplied(projectName string, compose *yaml.Node, services []string) error {
+	if len(services) == 0 {
+		yamlStr, err := renderYAML(compose)
+		if err != nil {
+			return err
+		}
+		return saveAppliedCompose(projectName, yamlStr)
+	}
+
+	applied := newMappingNode()
+	if yamlStr, err := loadAppliedCompose(projectName); err == nil {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(yamlStr), &doc); err != nil {
+			return err
+		}
+		if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+			applied = doc.Content[0]
+		}
+	}
+
+	appliedServices := mappingNode(applied, "services")
+	if appliedServices == nil {
+		appliedServices = newMappingNode()
+		applied.Content = append(applied.Content, scalarNode("services"), appliedServices)
+	}
+	current := mappingNode(compose, "services")
+	for _, key := range services {
+		svc := mappingNode(current, key)
+		if svc == nil {
+			continue
+		}
+		if idx := mappingIndex(appliedServices, key); idx >= 0 {
+			appliedServices.Content[idx+1] = cloneNode(svc)
+		} else {
+			appliedServices.Content = append(appliedServices.Content, scalarNode(key), cloneNode(svc))
+		}
+	}
+
+	yamlStr, err := renderYAML(applied)
+	if err != nil {
+		return err
+	}
+	return saveAppliedCompose(projectName, yamlStr)
+}
+
+func loadAppliedCompose(projectName string) (string, error) {
+	data, err := os.ReadFile(appliedComposePath(projectName))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
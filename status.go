@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OptionStatus summarises the Docker host state of a service's containers.
+type OptionStatus struct {
+	Total      int // expected containers, counting declared services with none
+	Running    int
+	Unhealthy  int
+	Starting   int // running, healthcheck not yet passed
+	Restarting int
+	Paused     int
+	Created    int
+	Exited     int
+	ExitCode   int // exit code of the first non-zero exited container
+	Stale      int // created from an outdated service definition
+
+	// ResourcesPresent is set when any declared network or volume exists.
+	ResourcesPresent bool
+}
+
+// optionStatus inspects the containers of opt, whose resolved YAML is
+// resolved. hashes holds the expected config hashes by project and service
+// key, see expectedHashes; it may be nil.
+func (a *App) optionStatus(opt *Option, resolved *yaml.Node, hashes map[string]map[string]string) OptionStatus {
+	var st OptionStatus
+	if a.dockerStatus == nil || resolved == nil {
+		return st
+	}
+
+	projectName := a.optionProject(opt).Name
+	for _, ref := range extractServiceRefs(resolved) {
+		containers := a.dockerStatus.ServiceContainers(projectName, ref.Key, ref.ContainerName)
+		if len(containers) == 0 {
+			st.Total++
+			continue
+		}
+		for _, c := range containers {
+			st.Total++
+			if opt.Enabled && isStale(c, hashes[projectName][ref.Key]) {
+				st.Stale++
+			}
+			switch c.State {
+			case "running":
+				st.Running++
+				switch c.Health {
+				case "unhealthy":
+					st.Unhealthy++
+				case "starting":
+					st.Starting++
+				}
+			case "restarting":
+				st.Restarting++
+			case "paused":
+				st.Paused++
+			case "created":
+				st.Created++
+			case "exited", "dead":
+				st.Exited++
+				if st.ExitCode == 0 {
+					st.ExitCode = c.ExitCode
+				}
+			}
+		}
+	}
+
+	for _, name := range extractNetworkNames(resolved, projectName) {
+		if a.dockerStatus.IsNetworkExists(name) {
+			st.ResourcesPresent = true
+		}
+	}
+	for _, name := range extractVolumeNames(resolved, projectName) {
+		if a.dockerStatus.IsVolumeExists(name) {
+			st.ResourcesPresent = true
+		}
+	}
+
+	return st
+}
+
+// Deployed reports whether any container of the service exists.
+func (st OptionStatus) Deployed() bool {
+	return st.Running+st.Restarting+st.Paused+st.Created+st.Exited > 0
+}
+
+// Healthy reports whether every expected container runs without a failing
+// healthcheck.
+func (st OptionStatus) Healthy() bool {
+	return st.Total > 0 && st.Running == st.Total && st.Unhealthy == 0
+}
+
+// Details lists the notable states worth showing next to the service name,
+// most severe first. A fully healthy or absent service has none.
+func (st OptionStatus) Details() []string {
+	if !st.Deployed() {
+		return nil
+	}
+
+	var details []string
+	if st.Running > 0 && st.Running < st.Total {
+		details = append(details, fmt.Sprintf("%d/%d running", st.Running, st.Total))
+	}
+	if st.Unhealthy > 0 {
+		details = append(details, "unhealthy")
+	}
+	if st.Restarting > 0 {
+		details = append(details, "restarting")
+	}
+	if st.Exited > 0 {
+		if st.ExitCode != 0 {
+			details = append(details, fmt.Sprintf("exited (code %d)", st.ExitCode))
+		} else {
+			details = append(details, "exited")
+		}
+	}
+	if st.Paused > 0 {
+		details = append(details, "paused")
+	}
+	if st.Created > 0 {
+		details = append(details, "created")
+	}
+	if st.Starting > 0 && st.Unhealthy == 0 {
+		details = append(details, "starting")
+	}
+	return details
+}
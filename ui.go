@@ -4,12 +4,16 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
+const notifyDuration = 4 * time.Second
+
 func (a *App) setupUI() {
 	a.app = tview.NewApplication()
 	selectionColor := tcell.NewRGBColor(68, 68, 88)
@@ -48,6 +52,7 @@ func (a *App) setupUI() {
 	// Preview panel
 	a.previewView = tview.NewTextView().
 		SetDynamicColors(true).
+		SetRegions(true).
 		SetWordWrap(true).
 		SetScrollable(true)
 	a.previewView.SetBorder(true).
@@ -65,6 +70,12 @@ func (a *App) setupUI() {
 		SetTitleAlign(tview.AlignLeft).
 		SetBorderColor(tcell.ColorDefault)
 
+	// Streaming log pane, swapped in place of the command log
+	a.logPane = newLogPane(a.config.LogTail)
+	a.logPages = tview.NewPages().
+		AddPage("log", a.logView, true, true).
+		AddPage("stream", a.logPane.view, true, false)
+
 	// Status bar
 	a.statusBar = tview.NewTextView().
 		SetDynamicColors(true).
@@ -81,7 +92,7 @@ func (a *App) setupUI() {
 
 	rightFlex := tview.NewFlex().SetDirection(tview.FlexRow).
 		AddItem(a.previewView, 0, 1, false).
-		AddItem(a.logView, 0, 1, false)
+		AddItem(a.logPages, 0, 1, false)
 
 	mainFlex := tview.NewFlex().SetDirection(tview.FlexColumn).
 		AddItem(leftFlex, 0, 1, true).
@@ -116,10 +127,21 @@ func (a *App) refreshOptionsList() {
 	currentIdx := a.optionsList.GetCurrentItem()
 	a.optionsList.Clear()
 
+	// Every option is read and merged once for the whole refresh.
+	r := a.newResolutions()
+	a.conflicts = a.analyseConflicts(r)
+	a.updateStatusBar()
+
 	options := a.getCurrentOptions()
+	hashes := a.expectedHashes(r)
 	for _, opt := range options {
-		running := a.isOptionRunning(opt)
-		label := formatOptionLabel(opt, running)
+		resolved, _ := r.resolve(opt)
+		label := formatOptionLabel(opt, a.optionStatus(opt, resolved, hashes))
+		if opt.Enabled {
+			if deps := a.disabledDependencies(opt); len(deps) > 0 {
+				label += fmt.Sprintf(" [red](needs %s)[-]", formatOptionRefs(deps))
+			}
+		}
 		a.optionsList.AddItem(label, "", 0, nil)
 	}
 
@@ -142,11 +164,19 @@ func (a *App) refreshAddonsList() {
 
 	for _, addon := range opt.Addons {
 		var label string
-		if opt.ActiveAddons[addon.Name] {
+		switch {
+		case addon.Group != "" && opt.ActiveAddons[addon.Name]:
+			label = fmt.Sprintf("[green](\u2022) %s %s[-]", addon.Group, addon.Label)
+		case addon.Group != "":
+			label = fmt.Sprintf("[white]( ) %s %s[-]", addon.Group, addon.Label)
+		case opt.ActiveAddons[addon.Name]:
 			label = fmt.Sprintf("[green]\u2713 %s %s[-]", addon.Label, addon.Name)
-		} else {
+		default:
 			label = fmt.Sprintf("[white]\u2717 %s %s[-]", addon.Label, addon.Name)
 		}
+		if note := opt.addonNote(addon.Name); note != "" {
+			label += fmt.Sprintf(" [gray](%s)[-]", note)
+		}
 		a.addonsList.AddItem(label, "", 0, nil)
 	}
 
@@ -158,13 +188,22 @@ func (a *App) refreshAddonsList() {
 	}
 }
 
-func formatOptionLabel(opt *Option, running bool) string {
+func formatOptionLabel(opt *Option, status OptionStatus) string {
 	var b strings.Builder
 
-	// Circle: indicates Docker host status (running/exists)
-	if running {
+	// Circle: indicates Docker host status. Filled means containers are
+	// running; dotted means only stopped containers, networks or volumes
+	// are left behind.
+	switch {
+	case status.Unhealthy > 0 || status.Restarting > 0 || status.ExitCode != 0:
+		b.WriteString("[red]\u25cf[-] ")
+	case status.Healthy():
 		b.WriteString("[green]\u25cf[-] ")
-	} else {
+	case status.Running > 0:
+		b.WriteString("[yellow]\u25d0[-] ")
+	case status.Deployed() || status.ResourcesPresent:
+		b.WriteString("[blue]\u25cc[-] ")
+	default:
 		b.WriteString("[white]\u25cb[-] ")
 	}
 
@@ -175,8 +214,15 @@ func formatOptionLabel(opt *Option, running bool) string {
 		b.WriteString(fmt.Sprintf("[white]%s[-]", opt.Name))
 	}
 
-	// Addon labels: indicate addon activation status
+	// Addon labels: indicate addon activation status. Groups show only
+	// their selected variant.
 	for _, addon := range opt.Addons {
+		if addon.Group != "" {
+			if opt.ActiveAddons[addon.Name] {
+				b.WriteString(fmt.Sprintf(" [green](%s:%s)[-]", addon.Group, addon.Label))
+			}
+			continue
+		}
 		if opt.ActiveAddons[addon.Name] {
 			b.WriteString(fmt.Sprintf(" [green](%s)[-]", addon.Label))
 		} else {
@@ -184,6 +230,16 @@ func formatOptionLabel(opt *Option, running bool) string {
 		}
 	}
 
+	// Drift: containers that need re-creating to match the configuration
+	if status.Stale > 0 {
+		b.WriteString(" [orange]stale[-]")
+	}
+
+	// Container breakdown: partial, unhealthy or stopped states
+	if details := status.Details(); len(details) > 0 {
+		b.WriteString(fmt.Sprintf(" [gray]%s[-]", strings.Join(details, ", ")))
+	}
+
 	return b.String()
 }
 
@@ -259,30 +315,108 @@ func (a *App) updatePreview() {
 		return
 	}
 
+	if a.diffMode != diffOff {
+		a.showDiffPreview(opt)
+		return
+	}
+
 	// Always show resolved compose for the selected option
-	a.previewView.SetTitle(fmt.Sprintf(" %s ", opt.Name))
+	title := fmt.Sprintf(" %s ", opt.Name)
+	if a.rawPreview {
+		title = fmt.Sprintf(" %s [raw] ", opt.Name)
+	}
+	a.previewView.SetTitle(tview.Escape(title))
 
-	resolved, err := resolveOption(opt)
+	origins := make(provenance)
+	resolved, err := traceOption(opt, origins, a.diagnostics)
 	if err != nil {
 		a.previewView.SetText(fmt.Sprintf("[red]Error: %v[-]", err))
 		return
 	}
 
+	var envErrs []error
+	if !a.rawPreview {
+		envErrs = a.interpolateOption(opt, resolved, origins)
+	}
+
 	yamlStr, err := renderYAML(resolved)
 	if err != nil {
 		a.previewView.SetText(fmt.Sprintf("[red]Error: %v[-]", err))
 		return
 	}
 
+	if a.annotate.enabled {
+		a.showAnnotatedPreview(opt, resolved, origins, yamlStr)
+		return
+	}
+
 	highlighted := highlightCode(yamlStr, "yaml")
+	if errs, err := validateCompose(resolved, origins); err == nil && len(errs) > 0 {
+		highlighted = formatSchemaErrors(errs) + highlighted
+	}
+	if len(envErrs) > 0 {
+		highlighted = formatEnvErrors(envErrs) + highlighted
+	}
 	a.previewView.SetText(highlighted)
 	a.previewView.ScrollToBeginning()
 }
 
+func formatEnvErrors(errs []error) string {
+	var b strings.Builder
+	for _, err := range errs {
+		b.WriteString(fmt.Sprintf("[red]✗ %s[-]\n", tview.Escape(err.Error())))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func formatSchemaErrors(errs []schemaError) string {
+	var b strings.Builder
+	for _, e := range errs {
+		b.WriteString(fmt.Sprintf("[red]✗ %s[-]\n", tview.Escape(e.String())))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
 // --- Status bar ---
 
+// updateStatusBar shows the key hints, preceded by a warning while enabled
+// services conflict. A transient notification is left alone.
 func (a *App) updateStatusBar() {
-	a.statusBar.SetText(" [yellow]j/k[-] nav  [yellow]space[-] toggle  [yellow]e[-] edit  [yellow]U[-]p [yellow]D[-]own=all  [yellow]s[-]top [yellow]c[-]ontinue [yellow]r[-]estart [yellow]p[-]ull  [yellow]SHIFT[-]=all  [yellow]y[-] copy  [yellow]?[-] help  [yellow]q[-] quit")
+	if a.notifying {
+		return
+	}
+	var counts []string
+	if n := len(a.diagnostics.list()); n > 0 {
+		counts = append(counts, fmt.Sprintf("[red]✗ %d error%s[-]", n, plural(n)))
+	}
+	if n := len(a.conflicts); n > 0 {
+		counts = append(counts, fmt.Sprintf("[orange]⚠ %d conflict%s[-]", n, plural(n)))
+	}
+	warning := ""
+	if len(counts) > 0 {
+		warning = " " + strings.Join(counts, " ") + " [yellow]![-] problems │"
+	}
+	a.statusBar.SetText(warning + " [yellow]j/k[-] nav  [yellow]space[-] toggle  [yellow]e[-] edit  [yellow]u[-]p [yellow]d[-]own [yellow]s[-]top [yellow]c[-]ontinue [yellow]r[-]estart [yellow]p[-]ull [yellow]b[-]uild  [yellow]SHIFT[-]=all  [yellow]y[-] copy  [yellow]?[-] help  [yellow]q[-] quit")
+}
+
+// notify shows a transient message in the status bar, restoring the key
+// hints after a few seconds unless another message replaced it.
+func (a *App) notify(color, msg string) {
+	a.notifySeq++
+	a.notifying = true
+	seq := a.notifySeq
+	a.statusBar.SetText(fmt.Sprintf(" [%s]%s[-]", color, tview.Escape(msg)))
+
+	time.AfterFunc(notifyDuration, func() {
+		a.app.QueueUpdateDraw(func() {
+			if a.notifySeq == seq {
+				a.notifying = false
+				a.updateStatusBar()
+			}
+		})
+	})
 }
 
 // --- Actions ---
@@ -292,8 +426,36 @@ func (a *App) toggleOption() {
 	if opt == nil {
 		return
 	}
-	opt.Enabled = !opt.Enabled
-	a.saveState()
+
+	if !opt.Enabled {
+		if deps := a.disabledDependencies(opt); len(deps) > 0 {
+			msg := fmt.Sprintf("[yellow::b]%s depends on[-:-:-]\n\n[green]%s[-]\n\nEnable them too? [yellow]n[-] enables %s alone.",
+				opt.Name, formatOptionRefs(deps), opt.Name)
+			a.showConfirm("Dependencies", msg, tcell.ColorYellow, func() {
+				for _, dep := range deps {
+					dep.Enabled = true
+				}
+				a.setOptionEnabled(opt, true)
+			}, func() {
+				a.setOptionEnabled(opt, true)
+			})
+			return
+		}
+	} else if dependents := a.enabledDependents(opt); len(dependents) > 0 {
+		msg := fmt.Sprintf("[yellow::b]%s is required by[-:-:-]\n\n[green]%s[-]\n\nDisable it anyway?",
+			opt.Name, formatOptionRefs(dependents))
+		a.showConfirm("Dependents", msg, tcell.ColorRed, func() {
+			a.setOptionEnabled(opt, false)
+		}, nil)
+		return
+	}
+
+	a.setOptionEnabled(opt, !opt.Enabled)
+}
+
+func (a *App) setOptionEnabled(opt *Option, enabled bool) {
+	opt.Enabled = enabled
+	a.diagnostics.check("state", a.stateFilePath(), a.saveState())
 	a.refreshAll()
 }
 
@@ -310,12 +472,26 @@ func (a *App) toggleAddon() {
 
 	addonName := opt.Addons[idx].Name
 	if opt.ActiveAddons[addonName] {
-		delete(opt.ActiveAddons, addonName)
+		removed, err := opt.disableAddon(addonName)
+		if err != nil {
+			a.notify("red", fmt.Sprintf("Cannot disable %s: %v", addonName, err))
+			return
+		}
+		if len(removed) > 0 {
+			a.notify("yellow", fmt.Sprintf("Also disabled %s (requires %s)", strings.Join(removed, ", "), addonName))
+		}
 	} else {
-		opt.ActiveAddons[addonName] = true
+		added, err := opt.enableAddon(addonName)
+		if err != nil {
+			a.notify("red", fmt.Sprintf("Cannot enable %s: %v", addonName, err))
+			return
+		}
+		if len(added) > 0 {
+			a.notify("yellow", fmt.Sprintf("Also enabled %s (needed by %s)", strings.Join(added, ", "), addonName))
+		}
 	}
 
-	a.saveState()
+	a.diagnostics.check("state", a.stateFilePath(), a.saveState())
 	a.refreshAddonsList()
 	a.refreshOptionsList()
 	a.updatePreview()
@@ -324,8 +500,15 @@ func (a *App) toggleAddon() {
 // --- Confirm modals ---
 
 func (a *App) showDockerConfirm(title, message string, borderColor tcell.Color, action func()) {
+	a.showConfirm(title, message, borderColor, action, nil)
+}
+
+// showConfirm opens a confirmation modal. When alt is set, pressing n runs
+// it instead of action; the message should say what it does.
+func (a *App) showConfirm(title, message string, borderColor tcell.Color, action, alt func()) {
 	a.confirmOpen = true
 	a.confirmAction = action
+	a.confirmAlt = alt
 
 	text := tview.NewTextView().
 		SetDynamicColors(true).
@@ -337,29 +520,101 @@ func (a *App) showDockerConfirm(title, message string, borderColor tcell.Color,
 		SetTitleAlign(tview.AlignCenter).
 		SetBorderColor(borderColor)
 
-	a.pages.AddPage("confirm", modal(text, 55, 9), true, true)
+	height := max(9, strings.Count(message, "\n")+6)
+	if alt != nil {
+		height = 11
+	}
+	a.pages.AddPage("confirm", modal(text, 55, height), true, true)
 	a.app.SetFocus(text)
 }
 
-func (a *App) confirmSingleAction(title, desc string, color tcell.Color, action func()) {
+// confirmSingleAction asks before running a compose command for the
+// services of the selected option. Services that are not enabled are not
+// part of the composition, so they are refused.
+func (a *App) confirmSingleAction(title string, color tcell.Color, args ...string) {
+	opt, msg, ok := a.singleActionMessage(title, args)
+	if !ok {
+		return
+	}
+	a.showDockerConfirm(title, msg, color, func() {
+		a.dockerComposeSingle(opt, args...)
+	})
+}
+
+// confirmUpSingle is confirmSingleAction for up, with the schema check of
+// confirmUp.
+func (a *App) confirmUpSingle() {
+	args := []string{"up", "-d"}
+	opt, msg, ok := a.singleActionMessage("Up", args)
+	if !ok {
+		return
+	}
+	a.confirmUp("Up", msg, func() {
+		a.dockerComposeSingle(opt, args...)
+	})
+}
+
+func (a *App) singleActionMessage(title string, args []string) (*Option, string, bool) {
 	opt := a.getSelectedOption()
 	if opt == nil {
+		return nil, "", false
+	}
+	if !opt.Enabled {
+		a.notify("yellow", fmt.Sprintf("%s is not enabled", optionRef(opt)))
+		return nil, "", false
+	}
+	resolved, err := resolveOption(opt, a.diagnostics)
+	if err != nil {
+		a.notify("red", fmt.Sprintf("%s: %v", optionRef(opt), err))
+		return nil, "", false
+	}
+	keys := serviceKeys(resolved)
+	if len(keys) == 0 {
+		a.notify("yellow", fmt.Sprintf("%s defines no services", optionRef(opt)))
+		return nil, "", false
+	}
+	msg := fmt.Sprintf("[yellow::b]%s[-:-:-]\n\nRun [green]docker compose %s[-] for [green]%s[-]?",
+		title, strings.Join(args, " "), tview.Escape(strings.Join(keys, " ")))
+	return opt, msg, true
+}
+
+// confirmUp asks before bringing services up. When enabled services fail
+// schema validation the errors are listed instead, and confirming runs
+// anyway.
+func (a *App) confirmUp(title, message string, run func()) {
+	problems := a.schemaProblems()
+	if len(problems) == 0 {
+		a.showDockerConfirm(title, message, tcell.ColorGreen, run)
 		return
 	}
-	msg := fmt.Sprintf("[yellow::b]%s[-:-:-]\n\nRun [green]docker %s[-] for [green]%s[-]?", title, desc, opt.Name)
-	a.showDockerConfirm(title, msg, color, action)
+
+	shown := problems
+	if len(shown) > 3 {
+		shown = append(shown[:3:3], fmt.Sprintf("… and %d more", len(problems)-3))
+	}
+	msg := fmt.Sprintf("[red::b]%s: invalid compose configuration[-:-:-]\n\n%s\n\nRun anyway?",
+		title, tview.Escape(strings.Join(shown, "\n")))
+	a.showDockerConfirm(title, msg, tcell.ColorRed, run)
+}
+
+func (a *App) confirmUpAll() {
+	msg := fmt.Sprintf("[yellow::b]Up All[-:-:-]\n\nRun [green]docker compose up -d[-] for all enabled services of project [green]%s[-]?", a.activeProject().Name)
+	a.confirmUp("Up All", msg, func() {
+		a.dockerComposeProject("up", "-d")
+	})
 }
 
 func (a *App) confirmGlobalAction(title, desc string, color tcell.Color, args ...string) {
-	msg := fmt.Sprintf("[yellow::b]%s[-:-:-]\n\nRun [green]docker compose %s[-] for all enabled services?", title, desc)
+	msg := fmt.Sprintf("[yellow::b]%s[-:-:-]\n\nRun [green]docker compose %s[-] for all enabled services of project [green]%s[-]?", title, desc, a.activeProject().Name)
 	a.showDockerConfirm(title, msg, color, func() {
-		a.dockerComposeGlobal(args...)
+		a.dockerComposeProject(args...)
 	})
 }
 
 func (a *App) closeConfirm() {
 	a.confirmOpen = false
 	a.confirmAction = nil
+	a.confirmAlt = nil
 	a.pages.RemovePage("confirm")
 	a.app.SetFocus(a.panels[a.currentPanelIdx])
 	a.updateBorderColors()
@@ -383,17 +638,34 @@ func (a *App) showHelp() {
 			"  Tab           Cycle panels\n" +
 			"  Esc           Back / quit\n\n" +
 			"[green]Docker (panel 1, lowercase=service, SHIFT=all):[-]\n" +
-			"  U             Up all (start containers)\n" +
-			"  D             Down all (remove containers)\n" +
+			"  u / U         Up (create and start)\n" +
+			"  d / D         Down (remove containers)\n" +
 			"  s / S         Stop containers\n" +
 			"  c / C         Continue (start stopped)\n" +
 			"  r / R         Restart containers\n" +
-			"  p / P         Pull images\n\n" +
+			"  p / P         Pull images\n" +
+			"  b / B         Build images\n" +
+			"  x             Shell into container\n" +
+			"  F             Up only stale services\n\n" +
 			"[green]Actions:[-]\n" +
 			"  Space / Enter Toggle item\n" +
-			"  e             Edit resource file\n" +
+			"  e             Edit file / source line\n" +
+			"  a             Annotate preview sources\n" +
+			"  =             Diff: addon toggle / applied\n" +
+			"  i             Raw / interpolated preview\n" +
+			"  L             Stream service logs\n" +
 			"  y             Copy preview YAML\n" +
-			"  Y             Copy global compose\n\n" +
+			"  Y             Copy global compose\n" +
+			"  v             Networks & volumes\n" +
+			"  !             Problems list\n" +
+			"  o             Jobs (show log, x cancel)\n" +
+			"  X             Interrupt job, again: kill\n\n" +
+			"[green]Log stream (while open):[-]\n" +
+			"  f             Follow / pause\n" +
+			"  t             Toggle timestamps\n" +
+			"  / n N         Search, next / prev match\n" +
+			"  + / -         Tail length\n" +
+			"  PgUp / PgDn   Scroll logs\n\n" +
 			"[green]Meta:[-]\n" +
 			"  q             Quit\n" +
 			"  ?             This help\n\n" +
@@ -404,7 +676,7 @@ func (a *App) showHelp() {
 		SetTitleAlign(tview.AlignCenter).
 		SetBorderColor(tcell.ColorGreen)
 
-	a.pages.AddPage("help", modal(helpText, 45, 25), true, true)
+	a.pages.AddPage("help", modal(helpText, 45, 43), true, true)
 	a.app.SetFocus(helpText)
 }
 
@@ -415,9 +687,138 @@ func (a *App) closeHelp() {
 	a.updateBorderColors()
 }
 
+// --- Resources view ---
+
+func (a *App) showResources() {
+	a.resourcesOpen = true
+
+	var b strings.Builder
+	b.WriteString("[yellow::b]Networks and volumes of enabled services[-:-:-]\n")
+
+	enabled := 0
+	for _, cat := range a.categories {
+		for _, opt := range a.options[cat.Name] {
+			if !opt.Enabled {
+				continue
+			}
+			enabled++
+			b.WriteString(fmt.Sprintf("\n[green]%s/%s[-]\n", cat.Name, opt.Name))
+
+			resolved, err := resolveOption(opt, a.diagnostics)
+			if err != nil {
+				b.WriteString(fmt.Sprintf("  [red]%s[-]\n", tview.Escape(err.Error())))
+				continue
+			}
+			projectName := a.optionProject(opt).Name
+			networks := extractNetworkNames(resolved, projectName)
+			volumes := extractVolumeNames(resolved, projectName)
+			if len(networks) == 0 && len(volumes) == 0 {
+				b.WriteString("  [gray]none declared[-]\n")
+				continue
+			}
+			sort.Strings(networks)
+			sort.Strings(volumes)
+			for _, name := range networks {
+				b.WriteString(a.formatResourceLine("network", name, a.dockerStatus != nil && a.dockerStatus.IsNetworkExists(name)))
+			}
+			for _, name := range volumes {
+				b.WriteString(a.formatResourceLine("volume", name, a.dockerStatus != nil && a.dockerStatus.IsVolumeExists(name)))
+			}
+		}
+	}
+	if enabled == 0 {
+		b.WriteString("\n[gray]No services enabled[-]\n")
+	}
+	b.WriteString("\n[white]Press Escape or q to close[-]")
+
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetText(b.String())
+
+	view.SetBorder(true).
+		SetTitle(" Resources ").
+		SetTitleAlign(tview.AlignCenter).
+		SetBorderColor(tcell.ColorGreen)
+
+	a.pages.AddPage("resources", modal(view, 70, 25), true, true)
+	a.app.SetFocus(view)
+}
+
+func (a *App) formatResourceLine(kind, name string, present bool) string {
+	if present {
+		return fmt.Sprintf("  [green]\u2713[-] %-8s %s\n", kind, tview.Escape(name))
+	}
+	return fmt.Sprintf("  [white]\u2717[-] %-8s %s [gray](absent)[-]\n", kind, tview.Escape(name))
+}
+
+func (a *App) closeResources() {
+	a.resourcesOpen = false
+	a.pages.RemovePage("resources")
+	a.app.SetFocus(a.panels[a.currentPanelIdx])
+	a.updateBorderColors()
+}
+
+// --- Problems list ---
+
+func (a *App) showProblems() {
+	a.problemsOpen = true
+
+	var b strings.Builder
+	if entries := a.diagnostics.list(); len(entries) > 0 {
+		b.WriteString("[yellow::b]Errors[-:-:-]\n\n")
+		for _, d := range entries {
+			b.WriteString(fmt.Sprintf("  [red]✗ %-9s[-] %s\n", d.Kind, tview.Escape(d.File)))
+			b.WriteString(fmt.Sprintf("    %s [gray]%s[-]\n", tview.Escape(d.Err.Error()), d.Time.Format("15:04:05")))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("[yellow::b]Conflicts between enabled services[-:-:-]\n\n")
+	if len(a.conflicts) == 0 {
+		b.WriteString("[gray]No conflicts[-]\n")
+	}
+	for _, c := range a.conflicts {
+		b.WriteString(fmt.Sprintf("  [orange]⚠ %-9s[-] %s\n", c.Kind, tview.Escape(c.Message)))
+	}
+	b.WriteString("\n[white]Press Escape or q to close[-]")
+
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWordWrap(true).
+		SetText(b.String())
+
+	view.SetBorder(true).
+		SetTitle(" Problems ").
+		SetTitleAlign(tview.AlignCenter).
+		SetBorderColor(tcell.ColorOrange)
+
+	a.pages.AddPage("problems", modal(view, 90, 25), true, true)
+	a.app.SetFocus(view)
+}
+
+func (a *App) closeProblems() {
+	a.problemsOpen = false
+	a.pages.RemovePage("problems")
+	a.app.SetFocus(a.panels[a.currentPanelIdx])
+	a.updateBorderColors()
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
 // --- Clipboard ---
 
 func (a *App) editResourceFile() {
+	if src, ok := a.annotatedSource(); ok {
+		a.openEditor(src.File, src.Line)
+		return
+	}
+
 	var filePath string
 	if a.currentPanelIdx == 0 {
 		opt := a.getSelectedOption()
@@ -432,14 +833,24 @@ func (a *App) editResourceFile() {
 		}
 		filePath = addon.File
 	}
+	a.openEditor(filePath, 0)
+}
 
+// openEditor suspends the UI to edit path in $EDITOR, positioned at line
+// when it is positive.
+func (a *App) openEditor(path string, line int) {
 	editor := os.Getenv("EDITOR")
 	if editor == "" {
 		editor = "vi"
 	}
 
+	args := []string{path}
+	if line > 0 {
+		args = []string{fmt.Sprintf("+%d", line), path}
+	}
+
 	a.app.Suspend(func() {
-		cmd := exec.Command(editor, filePath)
+		cmd := exec.Command(editor, args...)
 		cmd.Stdin = os.Stdin
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
@@ -456,9 +867,10 @@ func (a *App) copyPreviewToClipboard() {
 		}
 		data, err := os.ReadFile(addon.File)
 		if err != nil {
+			a.diagnostics.report("clipboard", addon.File, err)
 			return
 		}
-		copyToClipboard(string(data))
+		a.copyText(string(data))
 		return
 	}
 
@@ -466,27 +878,38 @@ func (a *App) copyPreviewToClipboard() {
 	if opt == nil {
 		return
 	}
-	resolved, err := resolveOption(opt)
+	resolved, err := resolveOption(opt, a.diagnostics)
 	if err != nil {
 		return
 	}
+	// Copy the YAML as the preview shows it.
+	if !a.rawPreview {
+		a.interpolateOption(opt, resolved, nil)
+	}
 	yamlStr, err := renderYAML(resolved)
 	if err != nil {
+		a.diagnostics.report("clipboard", opt.BaseFile, err)
 		return
 	}
-	copyToClipboard(yamlStr)
+	a.copyText(yamlStr)
 }
 
 func (a *App) copyGlobalComposeToClipboard() {
 	global, err := a.buildGlobalCompose()
 	if err != nil {
+		a.diagnostics.report("clipboard", "", err)
 		return
 	}
 	yamlStr, err := renderYAML(global)
 	if err != nil {
+		a.diagnostics.report("clipboard", "", err)
 		return
 	}
-	copyToClipboard(yamlStr)
+	a.copyText(yamlStr)
+}
+
+func (a *App) copyText(text string) {
+	a.diagnostics.check("clipboard", "", copyToClipboard(text))
 }
 
 // --- Modal helper ---
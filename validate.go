@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// composeSpecJSON is the Compose specification schema, as shipped with
+// compose-go.
+//
+//go:embed schema/compose-spec.json
+var composeSpecJSON string
+
+var composeSchema = sync.OnceValues(func() (*jsonschema.Schema, error) {
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource("compose-spec.json", strings.NewReader(composeSpecJSON)); err != nil {
+		return nil, err
+	}
+	return c.Compile("compose-spec.json")
+})
+
+// schemaError is a Compose schema violation in a resolved option, traced
+// back to the file that introduced the offending value.
+type schemaError struct {
+	Path    string
+	Message string
+	File    string
+	Line    int
+}
+
+func (e schemaError) String() string {
+	s := e.Path + ": " + e.Message
+	if e.File != "" {
+		s += fmt.Sprintf(" (%s:%d)", filepath.Base(e.File), e.Line)
+	}
+	return s
+}
+
+// validateOption checks the resolved YAML of opt against the Compose
+// schema.
+func validateOption(opt *Option, diags *diagnosticLog) ([]schemaError, error) {
+	origins := make(provenance)
+	resolved, err := traceOption(opt, origins, diags)
+	if err != nil {
+		return nil, err
+	}
+	return validateCompose(resolved, origins)
+}
+
+func validateCompose(doc *yaml.Node, origins provenance) ([]schemaError, error) {
+	schema, err := composeSchema()
+	if err != nil {
+		return nil, fmt.Errorf("loading compose schema: %w", err)
+	}
+
+	// The validator works on JSON values, so the YAML goes through JSON
+	// once. Numbers stay exact thanks to UseNumber.
+	var v interface{}
+	if err := doc.Decode(&v); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return []schemaError{{Path: "(root)", Message: err.Error()}}, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var inst interface{}
+	if err := dec.Decode(&inst); err != nil {
+		return nil, err
+	}
+
+	err = schema.Validate(inst)
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return nil, err
+	}
+
+	var result []schemaError
+	seen := make(map[string]bool)
+	for _, leaf := range schemaLeaves(ve) {
+		path := splitPointer(leaf.InstanceLocation)
+		if m := additionalPropertyRe.FindStringSubmatch(leaf.Message); m != nil {
+			path = append(path, m[1])
+		}
+		msg := leaf.Message
+		if msg == "oneOf failed" || msg == "anyOf failed" {
+			msg = "does not match any allowed form"
+		}
+		e := schemaError{Path: formatPath(path), Message: msg}
+		if n := nodeAt(doc, path); n != nil {
+			e.File, e.Line = origins[n], n.Line
+		}
+		if key := e.String(); !seen[key] {
+			seen[key] = true
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+var additionalPropertyRe = regexp.MustCompile(`^additionalProperties '([^']+)'`)
+
+// schemaLeaves returns the most specific causes of a validation error. For
+// a failed oneOf or anyOf, only the alternatives that got deepest into the
+// value are kept, as the others usually just failed on its type.
+func schemaLeaves(ve *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(ve.Causes) == 0 {
+		return []*jsonschema.ValidationError{ve}
+	}
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range ve.Causes {
+		leaves = append(leaves, schemaLeaves(cause)...)
+	}
+	if !strings.HasSuffix(ve.KeywordLocation, "/oneOf") && !strings.HasSuffix(ve.KeywordLocation, "/anyOf") {
+		return leaves
+	}
+
+	depth := func(e *jsonschema.ValidationError) int { return len(splitPointer(e.InstanceLocation)) }
+	deepest := depth(ve)
+	for _, leaf := range leaves {
+		deepest = max(deepest, depth(leaf))
+	}
+	if deepest == depth(ve) {
+		return []*jsonschema.ValidationError{ve}
+	}
+	var specific []*jsonschema.ValidationError
+	for _, leaf := range leaves {
+		if depth(leaf) == deepest {
+			specific = append(specific, leaf)
+		}
+	}
+	return specific
+}
+
+// splitPointer splits a JSON pointer into its unescaped tokens.
+func splitPointer(ptr string) []string {
+	if ptr == "" || ptr == "/" {
+		return nil
+	}
+	tokens := strings.Split(strings.TrimPrefix(ptr, "/"), "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens
+}
+
+func formatPath(path []string) string {
+	if len(path) == 0 {
+		return "(root)"
+	}
+	return strings.Join(path, ".")
+}
+
+// nodeAt follows path through doc. A mapping key at the end of the path
+// resolves to the key node, which marks where the key was written.
+func nodeAt(doc *yaml.Node, path []string) *yaml.Node {
+	n := doc
+	for i, token := range path {
+		switch n.Kind {
+		case yaml.MappingNode:
+			idx := mappingIndex(n, token)
+			if idx < 0 {
+				return n
+			}
+			if i == len(path)-1 {
+				return n.Content[idx]
+			}
+			n = n.Content[idx+1]
+		case yaml.SequenceNode:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(n.Content) {
+				return n
+			}
+			n = n.Content[idx]
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// schemaProblems validates every enabled option and returns the errors
+// prefixed with the service they belong to.
+func (a *App) schemaProblems() []string {
+	var problems []string
+	for _, cat := range a.categories {
+		for _, opt := range a.options[cat.Name] {
+			if !opt.Enabled {
+				continue
+			}
+			errs, err := validateOption(opt, a.diagnostics)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", optionRef(opt), err))
+				continue
+			}
+			for _, e := range errs {
+				problems = append(problems, fmt.Sprintf("%s: %s", optionRef(opt), e))
+			}
+		}
+	}
+	return problems
+}